@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"context"
+
+	ut "github.com/go-playground/universal-translator"
 	"github.com/sy264115809/logrush"
 	validator "gopkg.in/go-playground/validator.v9"
+	iris "gopkg.in/kataras/iris.v6"
 )
 
 // Base controller packages the common methods.
@@ -10,15 +14,27 @@ type Base struct {
 	L *logrush.Logger
 
 	validateFunc func(interface{}) error
+	translator   ut.Translator
 }
 
-var defaultValidator = validator.New().Struct
+var defaultValidator = func() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(jsonTagName)
+	return v
+}()
+
+// DefaultValidator returns the validator used by the Base controllers unless SetValidateFunc is called.
+// The translations for SetTranslator must be registered on it, e.g.
+// en_translations.RegisterDefaultTranslations(controllers.DefaultValidator(), trans)
+func DefaultValidator() *validator.Validate {
+	return defaultValidator
+}
 
 // New instances a new Base controller object.
 func New() *Base {
 	return &Base{
 		L:            logrush.StandardLogger(),
-		validateFunc: defaultValidator,
+		validateFunc: defaultValidator.Struct,
 	}
 }
 
@@ -27,6 +43,7 @@ func (c *Base) Copy(name string) *Base {
 	return &Base{
 		L:            c.L.Copy(name),
 		validateFunc: c.validateFunc,
+		translator:   c.translator,
 	}
 }
 
@@ -42,3 +59,17 @@ func (c *Base) SetValidateFunc(v func(interface{}) error) *Base {
 	c.validateFunc = v
 	return c
 }
+
+// SetTranslator sets the translator for the messages of validation errors, whose translations
+// must be registered on the validator, see DefaultValidator.
+// You can set it to nil to use the built-in english messages.
+func (c *Base) SetTranslator(trans ut.Translator) *Base {
+	c.translator = trans
+	return c
+}
+
+// Context returns the context of the request, which is canceled when the client's connection closes.
+// It can be passed straight through to the context-aware operations of mgobase.
+func (c *Base) Context(ctx *iris.Context) context.Context {
+	return ctx.Request.Context()
+}
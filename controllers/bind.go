@@ -0,0 +1,378 @@
+package controllers
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack"
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/mgo.v2/bson"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The struct tags used by Bind to fill fields from the path parameters and the url query.
+const (
+	TagParam = "param"
+	TagQuery = "query"
+)
+
+// ErrUnsupportedContentType represents the request body is in a format Bind can't decode.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Bind decodes the request body according to the Content-Type, fills the fields tagged with `param` and `query`
+// from the path parameters and the url query, and then validates obj once.
+//
+// The supported content types are json, xml, urlencoded form, multipart form, MessagePack and YAML.
+// The body is skipped if it's empty.
+func (c *Base) Bind(ctx *iris.Context, obj interface{}) (err error) {
+	if err = c.bindBody(ctx, obj); err != nil {
+		return
+	}
+
+	if err = bindTagged(obj, TagParam, func(name string) ([]string, bool) {
+		if val := ctx.Param(name); val != "" {
+			return []string{val}, true
+		}
+		return nil, false
+	}); err != nil {
+		return
+	}
+
+	if err = bindTagged(obj, TagQuery, queryLookup(ctx)); err != nil {
+		return
+	}
+
+	return c.Validate(obj)
+}
+
+func (c *Base) bindBody(ctx *iris.Context, obj interface{}) error {
+	if ctx.Request.Body == nil || ctx.Request.ContentLength == 0 {
+		return nil
+	}
+
+	contentType := ctx.Request.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && contentType != "" {
+		return &BindError{Format: contentType, Err: ErrUnsupportedContentType}
+	}
+
+	switch mediaType {
+	case "", MIMEJSON:
+		if err := ctx.ReadJSON(obj); err != nil {
+			return &BindError{Format: "json", Err: err}
+		}
+
+	case MIMEXML, MIMEXMLText:
+		if err := ctx.ReadXML(obj); err != nil {
+			return &BindError{Format: "xml", Err: err}
+		}
+
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		if err := ctx.ReadForm(obj); err != nil {
+			return &BindError{Format: "form", Err: err}
+		}
+
+	case MIMEMessagePack:
+		body, err := ioutil.ReadAll(ctx.Request.Body)
+		if err == nil {
+			err = msgpack.Unmarshal(body, obj)
+		}
+		if err != nil {
+			return &BindError{Format: "msgpack", Err: err}
+		}
+
+	case MIMEYAML, MIMEYAMLText:
+		body, err := ioutil.ReadAll(ctx.Request.Body)
+		if err == nil {
+			err = yaml.Unmarshal(body, obj)
+		}
+		if err != nil {
+			return &BindError{Format: "yaml", Err: err}
+		}
+
+	default:
+		return &BindError{Format: mediaType, Err: ErrUnsupportedContentType}
+	}
+
+	return nil
+}
+
+// BindQuery fills the fields tagged with `query` from the url query, and then validates obj.
+//
+// The tag is in form of `query:"name,default=value,layout=2006-01-02"`:
+// - `default` is used when the key is absent.
+// - the values of slice fields are separated by comma, both the requested ones and the default one.
+// - `layout` is used to parse time.Time fields, QueryFormatDateTime and RFC3339 are tried if it's not provided.
+//
+// The supported field types are string, ints, uints, floats, bool, time.Time, bson.ObjectId, encoding.TextUnmarshaler,
+// the pointers and slices of them, and nested structs whose fields are looked up with the `name.` prefix.
+// A nil pointer to nested struct is left nil if none of its keys is present, so its defaults are not applied.
+func (c *Base) BindQuery(ctx *iris.Context, obj interface{}) error {
+	if err := bindTagged(obj, TagQuery, queryLookup(ctx)); err != nil {
+		return err
+	}
+	return c.Validate(obj)
+}
+
+func queryLookup(ctx *iris.Context) func(name string) ([]string, bool) {
+	query := ctx.Request.URL.Query()
+	return func(name string) ([]string, bool) {
+		vals, ok := query[name]
+		return vals, ok
+	}
+}
+
+type tagOptions struct {
+	name       string
+	defaultVal *string
+	layout     string
+}
+
+// parseTag parses the tag value in form of `name,key=value,...`.
+// A segment without `=` belongs to the value of the previous option, so that the default value can contain commas.
+func parseTag(tag string) (opts tagOptions) {
+	segments := strings.Split(tag, ",")
+	opts.name = segments[0]
+
+	var last *string
+	for _, segment := range segments[1:] {
+		kv := strings.SplitN(segment, "=", 2)
+		if len(kv) != 2 {
+			if last != nil {
+				*last += "," + segment
+			}
+			continue
+		}
+
+		val := kv[1]
+		switch kv[0] {
+		case "default":
+			opts.defaultVal = &val
+			last = opts.defaultVal
+		case "layout":
+			opts.layout = val
+			last = &opts.layout
+		default:
+			last = nil
+		}
+	}
+	return
+}
+
+// bindTagged fills the fields of struct pointer obj which have the `tag` with the values returned by lookup.
+func bindTagged(obj interface{}, tag string, lookup func(name string) ([]string, bool)) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	_, err := bindStruct(v, tag, "", lookup)
+	return err
+}
+
+// bindStruct walks through the fields of struct v, the embedded structs share the prefix of v,
+// and the tagged nested structs are looked up with the `name.` prefix.
+// It returns true if any key of the fields is found, the nil struct pointers are only allocated in that case.
+func bindStruct(v reflect.Value, tag, prefix string, lookup func(name string) ([]string, bool)) (found bool, err error) {
+	if v.Kind() == reflect.Ptr {
+		if !v.IsNil() {
+			return bindStruct(v.Elem(), tag, prefix, lookup)
+		}
+
+		elem := reflect.New(v.Type().Elem())
+		if found, err = bindStruct(elem, tag, prefix, lookup); found && err == nil {
+			v.Set(elem)
+		}
+		return
+	}
+	if v.Kind() != reflect.Struct {
+		return false, nil
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf, fv := t.Field(i), v.Field(i)
+		// the unexported fields can't be set, except the embedded struct whose exported fields are promoted
+		if sf.PkgPath != "" && (!sf.Anonymous || sf.Type.Kind() == reflect.Ptr) {
+			continue
+		}
+
+		opts := parseTag(sf.Tag.Get(tag))
+		if opts.name == "-" {
+			continue
+		}
+
+		var nested bool
+		if opts.name == "" {
+			if sf.Anonymous && isNestedStruct(sf.Type) {
+				if nested, err = bindStruct(fv, tag, prefix, lookup); err != nil {
+					return
+				}
+				found = found || nested
+			}
+			continue
+		}
+
+		key := prefix + opts.name
+		if isNestedStruct(sf.Type) {
+			if nested, err = bindStruct(fv, tag, key+".", lookup); err != nil {
+				return
+			}
+			found = found || nested
+			continue
+		}
+
+		vals, ok := lookup(key)
+		if ok && len(vals) > 0 {
+			found = true
+		} else {
+			if opts.defaultVal == nil {
+				continue
+			}
+			vals = []string{*opts.defaultVal}
+		}
+
+		if isSlice(sf.Type) {
+			vals = splitValues(vals)
+		}
+		if err = setField(fv, vals, opts.layout); err != nil {
+			return found, &BindError{Format: tag, Err: fmt.Errorf("%s: %s", key, err)}
+		}
+	}
+	return
+}
+
+// splitValues splits every value by comma, so that `ids=1,2` is the same as `ids=1&ids=2`.
+func splitValues(vals []string) []string {
+	split := make([]string, 0, len(vals))
+	for _, val := range vals {
+		split = append(split, strings.Split(val, ",")...)
+	}
+	return split
+}
+
+var (
+	typeTime            = reflect.TypeOf(time.Time{})
+	typeObjectID        = reflect.TypeOf(bson.ObjectId(""))
+	typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isNestedStruct returns true if t is a struct or a pointer to struct which should be walked through,
+// rather than be parsed from a single value.
+func isNestedStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != typeTime && !reflect.PtrTo(t).Implements(typeTextUnmarshaler)
+}
+
+func isSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t != typeObjectID && !reflect.PtrTo(t).Implements(typeTextUnmarshaler)
+}
+
+// setField sets the string values to the field, the slice field takes all of them and others take the first one.
+func setField(fv reflect.Value, vals []string, layout string) error {
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), vals, layout); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+
+	if isSlice(fv.Type()) {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), val, layout); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	return setValue(fv, vals[0], layout)
+}
+
+func setValue(fv reflect.Value, val, layout string) error {
+	switch fv.Type() {
+	case typeTime:
+		date, err := parseTime(val, layout)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(date))
+		return nil
+
+	case typeObjectID:
+		if !bson.IsObjectIdHex(val) {
+			return fmt.Errorf("invalid object id %q", val)
+		}
+		fv.Set(reflect.ValueOf(bson.ObjectIdHex(val)))
+		return nil
+	}
+
+	if fv.CanAddr() {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(val))
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// parseTime parses val with the layout, or QueryFormatDateTime and RFC3339 if layout is empty.
+func parseTime(val, layout string) (date time.Time, err error) {
+	if layout != "" {
+		return time.Parse(layout, val)
+	}
+
+	for _, l := range []string{QueryFormatDateTime, time.RFC3339} {
+		if date, err = time.Parse(l, val); err == nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,167 @@
+package controllers_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func TestBind(t *testing.T) {
+	type param struct {
+		ID      int      `json:"id" param:"id"`
+		Name    string   `json:"name" xml:"name" yaml:"name" msgpack:"name" form:"name" validate:"required,eq=jacy"`
+		Age     int      `json:"age" xml:"age" yaml:"age" msgpack:"age" form:"age" validate:"gt=16"`
+		Tags    []string `json:"tags" query:"tag"`
+		Verbose bool     `json:"verbose" query:"verbose"`
+	}
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Post("/bind/:id", func(ctx *iris.Context) {
+		var p param
+		if err := baseController().Bind(ctx, &p); err != nil {
+			baseController().RespondError(ctx, err)
+			return
+		}
+		ctx.JSON(iris.StatusOK, p)
+	})
+
+	expected := map[string]interface{}{
+		"id":      42,
+		"name":    "jacy",
+		"age":     18,
+		"tags":    []string{"a", "b"},
+		"verbose": true,
+	}
+
+	type testcase struct {
+		desc        string
+		contentType string
+		body        string
+		status      int
+	}
+
+	testcases := []testcase{
+		{desc: "json", contentType: "application/json", body: `{"name":"jacy","age":18}`, status: iris.StatusOK},
+		{desc: "xml", contentType: "application/xml", body: `<param><name>jacy</name><age>18</age></param>`, status: iris.StatusOK},
+		{desc: "yaml", contentType: "application/x-yaml", body: "name: jacy\nage: 18\n", status: iris.StatusOK},
+		{desc: "msgpack", contentType: "application/x-msgpack", body: "\x82\xa4name\xa4jacy\xa3age\x12", status: iris.StatusOK},
+		{desc: "form", contentType: "application/x-www-form-urlencoded", body: "name=jacy&age=18", status: iris.StatusOK},
+		{desc: "malformed json", contentType: "application/json", body: `{"name":`, status: iris.StatusBadRequest},
+		{desc: "validation failed", contentType: "application/json", body: `{"name":"jack","age":18}`, status: iris.StatusUnprocessableEntity},
+		{desc: "unsupported content type", contentType: "application/pdf", body: "%PDF", status: iris.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range testcases {
+		res := httptest.New(app, t).POST("/bind/42").
+			WithQueryString("tag=a&tag=b&verbose=true").
+			WithHeader("Content-Type", tc.contentType).
+			WithBytes([]byte(tc.body)).
+			Expect().Status(tc.status)
+
+		if tc.status == iris.StatusOK {
+			res.JSON().Object().Equal(expected)
+		}
+	}
+
+	t.Run("bad query value", func(t *testing.T) {
+		httptest.New(app, t).POST("/bind/42").
+			WithQueryString("verbose=maybe").
+			WithJSON(map[string]interface{}{"name": "jacy", "age": 18}).
+			Expect().Status(iris.StatusBadRequest)
+	})
+}
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+func TestBindQuery(t *testing.T) {
+	type pagination struct {
+		Page  int `json:"page" query:"page,default=1"`
+		Limit int `json:"limit" query:"limit,default=20" validate:"lte=100"`
+	}
+
+	type filter struct {
+		pagination
+		Owner  bson.ObjectId `json:"owner" query:"owner"`
+		Score  float64       `json:"score" query:"score"`
+		Active *bool         `json:"active" query:"active"`
+		Since  time.Time     `json:"since" query:"since,layout=2006-01-02"`
+		Status []string      `json:"status" query:"status,default=open,pending"`
+		Level  level         `json:"level" query:"level"`
+		Range  struct {
+			Min int `json:"min" query:"min"`
+			Max int `json:"max" query:"max,default=10"`
+		} `json:"range" query:"range"`
+		Window *struct {
+			From int `json:"from" query:"from"`
+			To   int `json:"to" query:"to,default=9"`
+		} `json:"window" query:"window"`
+	}
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/filter", func(ctx *iris.Context) {
+		var f filter
+		if err := baseController().BindQuery(ctx, &f); err != nil {
+			baseController().RespondError(ctx, err)
+			return
+		}
+		ctx.JSON(iris.StatusOK, f)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		obj := httptest.New(app, t).GET("/filter").Expect().Status(iris.StatusOK).JSON().Object()
+		obj.Value("page").Equal(1)
+		obj.Value("limit").Equal(20)
+		obj.Value("active").Null()
+		obj.Value("status").Equal([]string{"open", "pending"})
+		obj.Value("range").Object().Equal(map[string]interface{}{"min": 0, "max": 10})
+		obj.Value("window").Null()
+	})
+
+	t.Run("values", func(t *testing.T) {
+		obj := httptest.New(app, t).GET("/filter").
+			WithQueryString("page=3&owner=5a1b2c3d4e5f6a7b8c9d0e1f&score=4.5&active=false&since=2018-01-02" +
+				"&status=closed&level=high&range.min=2&range.max=5&window.from=1").
+			Expect().Status(iris.StatusOK).JSON().Object()
+		obj.Value("page").Equal(3)
+		obj.Value("limit").Equal(20)
+		obj.Value("owner").Equal("5a1b2c3d4e5f6a7b8c9d0e1f")
+		obj.Value("score").Equal(4.5)
+		obj.Value("active").Equal(false)
+		obj.Value("since").Equal("2018-01-02T00:00:00Z")
+		obj.Value("status").Equal([]string{"closed"})
+		obj.Value("level").Equal(2)
+		obj.Value("range").Object().Equal(map[string]interface{}{"min": 2, "max": 5})
+		obj.Value("window").Object().Equal(map[string]interface{}{"from": 1, "to": 9})
+	})
+
+	t.Run("comma separated values", func(t *testing.T) {
+		obj := httptest.New(app, t).GET("/filter").WithQueryString("status=closed,open&status=done").
+			Expect().Status(iris.StatusOK).JSON().Object()
+		obj.Value("status").Equal([]string{"closed", "open", "done"})
+	})
+
+	for _, query := range []string{"page=x", "owner=123", "since=2018/01/02", "level=medium", "range.max=ten"} {
+		httptest.New(app, t).GET("/filter").WithQueryString(query).Expect().Status(iris.StatusBadRequest)
+	}
+	httptest.New(app, t).GET("/filter").WithQueryString("limit=1000").Expect().Status(iris.StatusUnprocessableEntity)
+}
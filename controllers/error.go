@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/sy264115809/golem/models/mgobase"
+
+	validator "gopkg.in/go-playground/validator.v9"
+	iris "gopkg.in/kataras/iris.v6"
+)
+
+type (
+	// ErrorMapping describes how an error is rendered to the client.
+	ErrorMapping struct {
+		Status  int
+		Code    Code
+		Message string
+	}
+
+	// ErrorMapper maps an error to ErrorMapping, the `ok` should be false if it can't handle the error.
+	ErrorMapper func(err error) (mapping ErrorMapping, ok bool)
+
+	// BindError represents the request body can't be decoded.
+	BindError struct {
+		Format string
+		Err    error
+	}
+
+	code struct {
+		code int
+		text string
+	}
+)
+
+// NewCode returns a Code with the given number and text.
+func NewCode(c int, text string) Code {
+	return code{
+		code: c,
+		text: text,
+	}
+}
+
+func (c code) Code() int {
+	return c.code
+}
+
+func (c code) Humanize() string {
+	return c.text
+}
+
+// The biz codes of the built-in error mappings.
+var (
+	CodeBadRequest             = NewCode(40000, "bad_request")
+	CodeInvalidID              = NewCode(40001, "invalid_id")
+	CodeBindFailed             = NewCode(40002, "bind_failed")
+	CodeValidationFailed       = NewCode(40003, "validation_failed")
+	CodeInvalidParam           = NewCode(40004, "invalid_param")
+	CodeNotFound               = NewCode(40400, "not_found")
+	CodeCanceled               = NewCode(40800, "canceled")
+	CodeDuplicateKey           = NewCode(40900, "duplicate_key")
+	CodeUnsupportedContentType = NewCode(41500, "unsupported_content_type")
+	CodeInternalError          = NewCode(50000, "internal_error")
+	CodeUnavailable            = NewCode(50300, "unavailable")
+	CodeTimeout                = NewCode(50400, "timeout")
+)
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("bind %s with error: %s", e.Format, e.Err)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errorMappersLock sync.RWMutex
+	errorMappers     []ErrorMapper
+)
+
+// RegisterErrorMapper registers a mapper for application's own error types.
+// The mappers registered later take precedence over the earlier ones and the built-in ones.
+func RegisterErrorMapper(mapper ErrorMapper) {
+	errorMappersLock.Lock()
+	defer errorMappersLock.Unlock()
+	errorMappers = append(errorMappers, mapper)
+}
+
+// RegisterError registers the mapping for the errors which are `target`, see `errors.Is`.
+func RegisterError(target error, mapping ErrorMapping) {
+	RegisterErrorMapper(func(err error) (ErrorMapping, bool) {
+		return mapping, errors.Is(err, target)
+	})
+}
+
+// MapError returns the ErrorMapping of err.
+// The unknown errors are mapped to 500 without exposing their messages.
+func MapError(err error) ErrorMapping {
+	errorMappersLock.RLock()
+	mappers := errorMappers
+	errorMappersLock.RUnlock()
+
+	for i := len(mappers) - 1; i >= 0; i-- {
+		if mapping, ok := mappers[i](err); ok {
+			return completeMapping(mapping, err)
+		}
+	}
+
+	mapping, ok := defaultErrorMapper(err)
+	if !ok {
+		return ErrorMapping{
+			Status:  iris.StatusInternalServerError,
+			Code:    CodeInternalError,
+			Message: http.StatusText(iris.StatusInternalServerError),
+		}
+	}
+	return completeMapping(mapping, err)
+}
+
+func completeMapping(mapping ErrorMapping, err error) ErrorMapping {
+	if mapping.Status == 0 {
+		mapping.Status = iris.StatusInternalServerError
+	}
+	if mapping.Message == "" {
+		mapping.Message = err.Error()
+	}
+	return mapping
+}
+
+func defaultErrorMapper(err error) (ErrorMapping, bool) {
+	var modelErr mgobase.ModelError
+	if errors.As(err, &modelErr) {
+		switch modelErr {
+		case mgobase.ErrInvalidID:
+			return ErrorMapping{Status: iris.StatusBadRequest, Code: CodeInvalidID}, true
+		case mgobase.ErrNotFound:
+			return ErrorMapping{Status: iris.StatusNotFound, Code: CodeNotFound}, true
+		case mgobase.ErrDuplicateKey:
+			return ErrorMapping{Status: iris.StatusConflict, Code: CodeDuplicateKey}, true
+		case mgobase.ErrNotConnected:
+			return ErrorMapping{Status: iris.StatusServiceUnavailable, Code: CodeUnavailable}, true
+		case mgobase.ErrDeadlineExceeded:
+			return ErrorMapping{Status: iris.StatusGatewayTimeout, Code: CodeTimeout}, true
+		case mgobase.ErrCanceled:
+			return ErrorMapping{Status: http.StatusRequestTimeout, Code: CodeCanceled}, true
+		}
+		return ErrorMapping{}, false
+	}
+
+	if errors.Is(err, ErrUnsupportedContentType) {
+		return ErrorMapping{Status: iris.StatusUnsupportedMediaType, Code: CodeUnsupportedContentType}, true
+	}
+
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return ErrorMapping{Status: iris.StatusBadRequest, Code: CodeBindFailed}, true
+	}
+
+	var paramErrs *ParamErrors
+	if errors.As(err, &paramErrs) {
+		return ErrorMapping{Status: iris.StatusBadRequest, Code: CodeInvalidParam}, true
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return ErrorMapping{Status: ValidationFailedStatus, Code: CodeValidationFailed}, true
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return ErrorMapping{Status: ValidationFailedStatus, Code: CodeValidationFailed}, true
+	}
+
+	var invalidValidationErr *validator.InvalidValidationError
+	if errors.As(err, &invalidValidationErr) {
+		return ErrorMapping{Status: iris.StatusBadRequest, Code: CodeBadRequest}, true
+	}
+
+	return ErrorMapping{}, false
+}
+
+// Error renders response according to the ErrorMapping of err, see `MapError`.
+// The code set by `WithCode` takes precedence over the mapped one.
+// The failing fields are listed in an `errors` array if err is *ValidationError or *ParamErrors.
+func (r *Response) Error(err error) {
+	mapping := MapError(err)
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && mapping.Status == ValidationFailedStatus {
+		r.data["errors"] = validationErr.Errors
+	}
+
+	var paramErrs *ParamErrors
+	if errors.As(err, &paramErrs) && mapping.Status == iris.StatusBadRequest {
+		r.data["errors"] = paramErrs.Errors
+	}
+
+	if r.code == nil {
+		r.code = mapping.Code
+	}
+	r.render(mapping.Status, mapping.Message)
+}
+
+// RespondError is a shortcut of `c.Response(ctx).Error(err)`.
+func (c *Base) RespondError(ctx *iris.Context, err error) {
+	c.Response(ctx).Error(err)
+}
@@ -0,0 +1,79 @@
+package controllers_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sy264115809/golem/controllers"
+	"github.com/sy264115809/golem/models/mgobase"
+
+	"github.com/stretchr/testify/assert"
+	validator "gopkg.in/go-playground/validator.v9"
+	iris "gopkg.in/kataras/iris.v6"
+)
+
+func TestMapError(t *testing.T) {
+	type testcase struct {
+		desc     string
+		err      error
+		expected controllers.ErrorMapping
+	}
+
+	validationErr := validator.New().Struct(struct {
+		Name string `validate:"required"`
+	}{})
+
+	testcases := []testcase{
+		{
+			desc:     "invalid id",
+			err:      mgobase.ErrInvalidID,
+			expected: controllers.ErrorMapping{Status: iris.StatusBadRequest, Code: controllers.CodeInvalidID, Message: "invalid object id"},
+		},
+		{
+			desc:     "wrapped not found",
+			err:      fmt.Errorf("find user: %w", mgobase.ErrNotFound),
+			expected: controllers.ErrorMapping{Status: iris.StatusNotFound, Code: controllers.CodeNotFound, Message: "find user: not found"},
+		},
+		{
+			desc:     "duplicate key",
+			err:      mgobase.ErrDuplicateKey,
+			expected: controllers.ErrorMapping{Status: iris.StatusConflict, Code: controllers.CodeDuplicateKey, Message: "duplicate key"},
+		},
+		{
+			desc:     "bind error",
+			err:      &controllers.BindError{Format: "json", Err: errors.New("unexpected EOF")},
+			expected: controllers.ErrorMapping{Status: iris.StatusBadRequest, Code: controllers.CodeBindFailed, Message: "bind json with error: unexpected EOF"},
+		},
+		{
+			desc:     "validation errors",
+			err:      validationErr,
+			expected: controllers.ErrorMapping{Status: iris.StatusUnprocessableEntity, Code: controllers.CodeValidationFailed, Message: validationErr.Error()},
+		},
+		{
+			desc:     "param errors",
+			err:      &controllers.ParamErrors{Errors: []controllers.ParamError{{Key: "page", Value: "x", Reason: "invalid syntax"}}},
+			expected: controllers.ErrorMapping{Status: iris.StatusBadRequest, Code: controllers.CodeInvalidParam, Message: `page: invalid value "x", invalid syntax`},
+		},
+		{
+			desc:     "unknown error",
+			err:      errors.New("secret"),
+			expected: controllers.ErrorMapping{Status: iris.StatusInternalServerError, Code: controllers.CodeInternalError, Message: "Internal Server Error"},
+		},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expected, controllers.MapError(tc.err), tc.desc)
+	}
+
+	t.Run("registered error", func(t *testing.T) {
+		t.Cleanup(controllers.SnapshotErrorMappers())
+
+		errQuota := errors.New("quota exceeded")
+		codeQuota := controllers.NewCode(42900, "quota_exceeded")
+		controllers.RegisterError(errQuota, controllers.ErrorMapping{Status: 429, Code: codeQuota})
+
+		assert.Equal(t, controllers.ErrorMapping{Status: 429, Code: codeQuota, Message: "quota exceeded"}, controllers.MapError(errQuota))
+		assert.Equal(t, controllers.CodeNotFound, controllers.MapError(mgobase.ErrNotFound).Code)
+	})
+}
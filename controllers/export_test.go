@@ -0,0 +1,27 @@
+package controllers
+
+// SnapshotErrorMappers saves the registered error mappers and returns the func restoring them.
+func SnapshotErrorMappers() (restore func()) {
+	errorMappersLock.RLock()
+	mappers := errorMappers[:len(errorMappers):len(errorMappers)]
+	errorMappersLock.RUnlock()
+
+	return func() {
+		errorMappersLock.Lock()
+		defer errorMappersLock.Unlock()
+		errorMappers = mappers
+	}
+}
+
+// SnapshotRenderers saves the registered renderers and returns the func restoring them.
+func SnapshotRenderers() (restore func()) {
+	renderersLock.RLock()
+	entries := append([]renderEntry(nil), renderers...)
+	renderersLock.RUnlock()
+
+	return func() {
+		renderersLock.Lock()
+		defer renderersLock.Unlock()
+		renderers = entries
+	}
+}
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sy264115809/golem/utils/bsonbuilder"
+)
+
+// QueryKeyFilter is the key of the RSQL/FIQL filter parameter, e.g. `?_filter=status==open,priority=gt=3`.
+// The `;` must be escaped as `%3B` in the url, since it's no longer a query separator.
+var QueryKeyFilter = "_filter"
+
+// filterComparators maps the RSQL/FIQL comparison operators to bsonbuilder operators.
+var filterComparators = map[string]bsonbuilder.Operator{
+	"==":       bsonbuilder.OperatorEq,
+	"!=":       bsonbuilder.OperatorNe,
+	"=gt=":     bsonbuilder.OperatorGt,
+	">":        bsonbuilder.OperatorGt,
+	"=ge=":     bsonbuilder.OperatorGte,
+	">=":       bsonbuilder.OperatorGte,
+	"=lt=":     bsonbuilder.OperatorLt,
+	"<":        bsonbuilder.OperatorLt,
+	"=le=":     bsonbuilder.OperatorLte,
+	"<=":       bsonbuilder.OperatorLte,
+	"=in=":     bsonbuilder.OperatorIn,
+	"=out=":    bsonbuilder.OperatorNin,
+	"=like=":   bsonbuilder.OperatorLike,
+	"=ilike=":  bsonbuilder.OperatorILike,
+	"=prefix=": bsonbuilder.OperatorPrefix,
+	"=exists=": bsonbuilder.OperatorExists,
+	"=size=":   bsonbuilder.OperatorSize,
+	"=all=":    bsonbuilder.OperatorAll,
+	"=type=":   bsonbuilder.OperatorType,
+	"=mod=":    bsonbuilder.OperatorMod,
+}
+
+type (
+	// filterNode is a node of the parsed filter, which is either a comparison or a logical group.
+	filterNode struct {
+		// the comparison
+		selector string
+		op       bsonbuilder.Operator
+		args     []string
+
+		// the logical group, `;` for and, `,` for or
+		logic    byte
+		children []*filterNode
+	}
+
+	filterParser struct {
+		input string
+		pos   int
+	}
+
+	filterSyntaxError struct {
+		pos     int
+		message string
+	}
+)
+
+func (e *filterSyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d, %s", e.pos, e.message)
+}
+
+// parseFilter parses the RSQL/FIQL filter into Query, the comparisons on fields are checked by the schema if any.
+// The grammar:
+//
+//	or         = and *( "," and )
+//	and        = constraint *( ";" constraint )
+//	constraint = "(" or ")" / comparison
+//	comparison = selector comparator ( argument / "(" argument *( "," argument ) ")" )
+//
+// The arguments could be quoted by `'` or `"`, in which the reserved characters are permitted and `\` escapes.
+func parseFilter(opts QueryOptions, filter string) (bsonbuilder.Query, *ParamError) {
+	p := &filterParser{input: filter}
+	node, err := p.parseOr()
+	if err == nil && p.pos < len(p.input) {
+		err = p.errorf("unexpected %q", p.input[p.pos])
+	}
+	if err != nil {
+		return nil, &ParamError{Key: QueryKeyFilter, Value: filter, Reason: err.Error()}
+	}
+	return node.toQuery(opts, filter)
+}
+
+// droppedFilters returns the errors of the filters in rawQuery which are dropped by url.ParseQuery,
+// since they contain the unescaped `;`.
+func droppedFilters(rawQuery string) []ParamError {
+	var errs []ParamError
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if !strings.Contains(pair, ";") {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if key, err := url.QueryUnescape(kv[0]); err != nil || key != QueryKeyFilter {
+			continue
+		}
+
+		var val string
+		if len(kv) == 2 {
+			val = kv[1]
+			if unescaped, err := url.QueryUnescape(val); err == nil {
+				val = unescaped
+			}
+		}
+		errs = append(errs, ParamError{Key: QueryKeyFilter, Value: val, Reason: "`;` must be escaped as %3B"})
+	}
+	return errs
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return &filterSyntaxError{pos: p.pos, message: fmt.Sprintf(format, args...)}
+}
+
+func (p *filterParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *filterParser) parseOr() (*filterNode, error) {
+	return p.parseGroup(',', p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (*filterNode, error) {
+	return p.parseGroup(';', p.parseConstraint)
+}
+
+// parseGroup parses the operands separated by sep, the group is skipped if there is only one operand.
+func (p *filterParser) parseGroup(sep byte, operand func() (*filterNode, error)) (*filterNode, error) {
+	node, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	group := &filterNode{logic: sep, children: []*filterNode{node}}
+	for p.peek() == sep {
+		p.pos++
+		if node, err = operand(); err != nil {
+			return nil, err
+		}
+		group.children = append(group.children, node)
+	}
+
+	if len(group.children) == 1 {
+		return node, nil
+	}
+	return group, nil
+}
+
+func (p *filterParser) parseConstraint() (*filterNode, error) {
+	if p.peek() != '(' {
+		return p.parseComparison()
+	}
+
+	p.pos++
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ')' {
+		return nil, p.errorf("missing ')'")
+	}
+	p.pos++
+	return node, nil
+}
+
+func (p *filterParser) parseComparison() (*filterNode, error) {
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune("=!<>;,()'\"", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	selector := strings.TrimSpace(p.input[start:p.pos])
+	if selector == "" {
+		return nil, p.errorf("missing selector")
+	}
+
+	op, err := p.parseComparator()
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	return &filterNode{selector: selector, op: op, args: args}, nil
+}
+
+func (p *filterParser) parseComparator() (bsonbuilder.Operator, error) {
+	start := p.pos
+	rest := p.input[p.pos:]
+
+	var comparator string
+	switch {
+	case strings.HasPrefix(rest, "=="), strings.HasPrefix(rest, "!="),
+		strings.HasPrefix(rest, ">="), strings.HasPrefix(rest, "<="):
+		comparator = rest[:2]
+	case strings.HasPrefix(rest, ">"), strings.HasPrefix(rest, "<"):
+		comparator = rest[:1]
+	case strings.HasPrefix(rest, "="):
+		if end := strings.IndexByte(rest[1:], '='); end >= 0 {
+			comparator = rest[:end+2]
+		}
+	}
+
+	op, ok := filterComparators[comparator]
+	if !ok {
+		return 0, p.errorf("unknown comparator at %q", rest)
+	}
+	p.pos = start + len(comparator)
+	return op, nil
+}
+
+func (p *filterParser) parseArguments() ([]string, error) {
+	if p.peek() != '(' {
+		arg, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		return []string{arg}, nil
+	}
+
+	p.pos++
+	var args []string
+	for {
+		arg, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		default:
+			return nil, p.errorf("missing ')'")
+		}
+	}
+}
+
+func (p *filterParser) parseArgument() (string, error) {
+	quote := p.peek()
+	if quote != '\'' && quote != '"' {
+		start := p.pos
+		for p.pos < len(p.input) && !strings.ContainsRune(";,()'\"", rune(p.input[p.pos])) {
+			p.pos++
+		}
+		if p.pos == start {
+			return "", p.errorf("missing argument")
+		}
+		return p.input[start:p.pos], nil
+	}
+
+	p.pos++
+	var arg strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && p.pos < len(p.input):
+			arg.WriteByte(p.input[p.pos])
+			p.pos++
+		case c == quote:
+			return arg.String(), nil
+		default:
+			arg.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated quoted argument")
+}
+
+// toQuery converts the node into Query. The comparisons of an and group are added to the same query unless the
+// operator is repeated on the field, which are added by `And` to keep their meanings.
+func (n *filterNode) toQuery(opts QueryOptions, filter string) (bsonbuilder.LogicalQuery, *ParamError) {
+	q := bsonbuilder.New()
+	if err := n.addTo(q, opts, filter); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// addTo adds the node to q, see toQuery.
+func (n *filterNode) addTo(q bsonbuilder.LogicalQuery, opts QueryOptions, filter string) *ParamError {
+	switch {
+	case n.children == nil:
+		return n.addComparison(q, opts, filter)
+
+	case n.logic == ',':
+		subs := make([]bsonbuilder.Query, len(n.children))
+		for i, child := range n.children {
+			sub, err := child.toQuery(opts, filter)
+			if err != nil {
+				return err
+			}
+			subs[i] = sub
+		}
+		q.Or(subs...)
+		return nil
+	}
+
+	added := make(map[string]bool)
+	for _, child := range n.children {
+		key := fmt.Sprintf("%s %d", child.selector, child.op)
+		if child.children == nil && added[key] {
+			sub, err := child.toQuery(opts, filter)
+			if err != nil {
+				return err
+			}
+			q.And(sub)
+			continue
+		}
+
+		added[key] = true
+		if err := child.addTo(q, opts, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addComparison adds the comparison to q, the selector is mapped and the arguments are parsed like QueryBSON does.
+func (n *filterNode) addComparison(q bsonbuilder.Query, opts QueryOptions, filter string) *ParamError {
+	field, vals, err := parseQueryValues(opts, QueryKeyFilter, n.selector, "", n.op, n.args)
+	if err != nil {
+		err.Value = filter
+		return err
+	}
+	q.Add(field, n.op, vals...)
+	return nil
+}
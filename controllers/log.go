@@ -3,17 +3,27 @@ package controllers
 import (
 	"time"
 
+	"github.com/sy264115809/golem/middlewares/access"
 	"github.com/sy264115809/logrush"
 	iris "gopkg.in/kataras/iris.v6"
 )
 
 // LogWithCtx returns *log.Entry with fields converted from context json format body.
+// The request id will be attached as `req_id` if it's set by the access middleware.
 func (c *Base) LogWithCtx(ctx *iris.Context) *logrush.Entry {
+	var entry *logrush.Entry
+
 	var reqCtx interface{}
 	if err := ctx.ReadJSON(&reqCtx); err != nil {
-		return c.L.WithField("req_ctx", err.Error())
+		entry = c.L.WithField("req_ctx", err.Error())
+	} else {
+		entry = c.L.WithField("req_ctx", reqCtx)
+	}
+
+	if reqID := access.RequestID(ctx); reqID != "" {
+		entry = entry.WithField("req_id", reqID)
 	}
-	return c.L.WithField("req_ctx", reqCtx)
+	return entry
 }
 
 // LogRunningTime logs the running time from paramter now to the time this function called.
@@ -0,0 +1,40 @@
+package controllers_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/sy264115809/golem/controllers"
+	"github.com/sy264115809/golem/middlewares/access"
+
+	"github.com/stretchr/testify/assert"
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func TestLogWithCtx(t *testing.T) {
+	base := controllers.New()
+
+	var fields []map[string]interface{}
+	handler := func(ctx *iris.Context) {
+		fields = append(fields, base.LogWithCtx(ctx).Data)
+	}
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Post("/plain", handler)
+	app.Post("/access", access.NewWithConfig(access.Config{Out: ioutil.Discard}), handler)
+
+	e := httptest.New(app, t)
+	e.POST("/plain").WithJSON(map[string]interface{}{"name": "jack"}).Expect()
+	e.POST("/access").WithHeader("X-Request-ID", "upstream-1").WithJSON(map[string]interface{}{"name": "jack"}).Expect()
+
+	if assert.Len(t, fields, 2) {
+		assert.Equal(t, map[string]interface{}{"name": "jack"}, fields[0]["req_ctx"])
+		assert.NotContains(t, fields[0], "req_id")
+
+		assert.Equal(t, map[string]interface{}{"name": "jack"}, fields[1]["req_ctx"])
+		assert.Equal(t, "upstream-1", fields[1]["req_id"])
+	}
+}
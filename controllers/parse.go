@@ -2,12 +2,16 @@ package controllers
 
 import (
 	"fmt"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/sy264115809/golem/models/mgobase"
 	"github.com/sy264115809/golem/utils/bsonbuilder"
 
+	validator "gopkg.in/go-playground/validator.v9"
 	iris "gopkg.in/kataras/iris.v6"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -17,17 +21,24 @@ import (
 /***********************************************/
 
 // Validate validates struct type obj.
+// The validator.ValidationErrors will be converted to *ValidationError which lists every failing field,
+// use errors.As to get the validator.ValidationErrors instead of the type assertion.
 func (c *Base) Validate(obj interface{}) (err error) {
-	if c.validateFunc != nil {
-		return c.validateFunc(obj)
+	if c.validateFunc == nil {
+		return nil
 	}
-	return nil
+
+	err = c.validateFunc(obj)
+	if errs, ok := err.(validator.ValidationErrors); ok {
+		return newValidationError(errs, c.translator)
+	}
+	return err
 }
 
 // BindJSON binds application/json content-type body and validates it
 func (c *Base) BindJSON(ctx *iris.Context, obj interface{}) (err error) {
 	if err = ctx.ReadJSON(&obj); err != nil {
-		return
+		return &BindError{Format: "json", Err: err}
 	}
 
 	return c.Validate(obj)
@@ -36,7 +47,7 @@ func (c *Base) BindJSON(ctx *iris.Context, obj interface{}) (err error) {
 // BindXML binds application/xml content-type body and validates it
 func (c *Base) BindXML(ctx *iris.Context, obj interface{}) (err error) {
 	if err = ctx.ReadXML(&obj); err != nil {
-		return
+		return &BindError{Format: "xml", Err: err}
 	}
 
 	return c.Validate(obj)
@@ -45,7 +56,7 @@ func (c *Base) BindXML(ctx *iris.Context, obj interface{}) (err error) {
 // BindForm binds application/x-www-form-urlencode content-type body and validates it
 func (c *Base) BindForm(ctx *iris.Context, obj interface{}) (err error) {
 	if err = ctx.ReadForm(obj); err != nil {
-		return
+		return &BindError{Format: "form", Err: err}
 	}
 	return c.Validate(obj)
 }
@@ -265,6 +276,12 @@ var (
 	DefaultPaginationLimit = 20
 )
 
+var (
+	// QueryKeyCursor is the key of the cursor parameter of marker pagination, which is used with QueryKeyPage,
+	// e.g. `?_cursor=xxx&_page=next`, see Base.Marker.
+	QueryKeyCursor = "_cursor"
+)
+
 var (
 	// QueryKeySort is the key of sort parameter which defines what field should be sorted on.
 	QueryKeySort = "_sort"
@@ -276,11 +293,41 @@ var (
 	OrderDescending = "desc"
 )
 
+var (
+	// QueryKeySearch is the key of full text search parameter, e.g. `?q=coffee`.
+	QueryKeySearch = "q"
+	// QueryKeyNear is the key of geo near parameter in form of `lng,lat`.
+	QueryKeyNear = "near"
+	// QueryKeyMaxDistance is the key of the max distance in meters from the point of QueryKeyNear.
+	QueryKeyMaxDistance = "_maxDistance"
+	// QueryKeyWithin is the key of geo within parameter in form of `lng,lat,radius`, `lng1,lat1,lng2,lat2`
+	// or the points of a polygon.
+	QueryKeyWithin = "within"
+	// QuerySortTextScore is the sort field represents the text search score, e.g. `?q=coffee&_sort=_score`.
+	QuerySortTextScore = "_score"
+	// TextScoreField is the field which the text search score should be projected into, see bsonbuilder.TextScore.
+	TextScoreField = "score"
+)
+
 var (
 	// QueryFormatDateTime is the formatter to parse the datetime parameter.
 	QueryFormatDateTime = "2006-01-02T15:04:05"
 )
 
+// reserved returns true if key is one of the pagination, sort and filter parameters,
+// or the search and geo parameters enabled by opts.
+func (opts QueryOptions) reserved(key string) bool {
+	switch key {
+	case QueryKeyPage, QueryKeyLimit, QueryKeyCursor, QueryKeySort, QueryKeyOrder, QueryKeyFilter:
+		return true
+	case QueryKeySearch:
+		return opts.TextSearch
+	case QueryKeyNear, QueryKeyMaxDistance, QueryKeyWithin:
+		return opts.GeoField != ""
+	}
+	return false
+}
+
 // Pagination parses the pagination info from query params.
 func (c *Base) Pagination(ctx *iris.Context) (page, skip, limit int) {
 	page = c.QueryInt(ctx, QueryKeyPage, DefaultPaginationPage)
@@ -297,6 +344,50 @@ func (c *Base) Pagination(ctx *iris.Context) (page, skip, limit int) {
 	return
 }
 
+// Marker parses the marker pagination info from query params, the limit could be got by Pagination.
+// The defaultField is the field of marker, or the comma separated fields of compound marker, e.g. `-created_at,-_id`,
+// see mgobase.NewCompoundMarker.
+// The valid forms:
+// - ?_page=first or no parameter: the first page
+// - ?_page=last: the last page
+// - ?_cursor=xxx&_page=next or ?_cursor=xxx: the page after the cursor
+// - ?_cursor=xxx&_page=prev: the page before the cursor
+// The *ParamErrors is returned if the page or cursor is malformed.
+func (c *Base) Marker(ctx *iris.Context, defaultField string) (mgobase.CompoundMarker, error) {
+	cursor := c.QueryString(ctx, QueryKeyCursor, "")
+
+	var page string
+	switch p := strings.ToLower(c.QueryString(ctx, QueryKeyPage, "")); p {
+	case "":
+		page = mgobase.PageFirst
+		if cursor != "" {
+			page = mgobase.PageNext
+		}
+	case mgobase.PageFirst, mgobase.PageLast, mgobase.PageNext:
+		page = p
+	case "prev", mgobase.PagePrev:
+		page = mgobase.PagePrev
+	default:
+		return nil, &ParamErrors{Errors: []ParamError{
+			{Key: QueryKeyPage, Value: p, Reason: "expect first, last, next or prev"},
+		}}
+	}
+
+	if cursor == "" && (page == mgobase.PageNext || page == mgobase.PagePrev) {
+		return nil, &ParamErrors{Errors: []ParamError{
+			{Key: QueryKeyCursor, Reason: fmt.Sprintf("cursor is required for the %s page", page)},
+		}}
+	}
+
+	marker, err := mgobase.ParseMarker(cursor, page, strings.Split(defaultField, ",")...)
+	if err != nil {
+		return nil, &ParamErrors{Errors: []ParamError{
+			{Key: QueryKeyCursor, Value: cursor, Reason: err.Error()},
+		}}
+	}
+	return marker, nil
+}
+
 // Sort parses the sort info from query params.
 // Suppose the sort settings are all defaults, there are two valid forms:
 // - json-server style: ?_sort=filed&order=DESC
@@ -326,7 +417,17 @@ func (c *Base) Sort(ctx *iris.Context) (sort, order string) {
 // 3. Else the order will be considered:
 //   3.1 If the order is `DESC`, prepend a minus to the sort parameter.
 //   3.2 return an array contains the (may be changed)sort parameter.
+// The QuerySortTextScore is converted to `$textScore:score` which is always in descending order,
+// the TextScoreField should be selected by bsonbuilder.TextScore.
 func (c *Base) SortAsMultiple(ctx *iris.Context) (sorts []string) {
+	defer func() {
+		for i, s := range sorts {
+			if strings.TrimPrefix(s, "-") == QuerySortTextScore {
+				sorts[i] = "$textScore:" + TextScoreField
+			}
+		}
+	}()
+
 	sort := c.QueryString(ctx, QueryKeySort, "")
 	if sort != "" {
 		if s := strings.Split(sort, ","); len(s) > 1 {
@@ -349,95 +450,387 @@ func (c *Base) SortAsMultiple(ctx *iris.Context) (sorts []string) {
 // - Add `_ne` to exclude a value
 // - Add `_like` to filter (RegExp supported)
 // - Use `.` to access deep properties
+//
+// The query fails closed, it matches nothing if any parameter is rejected by QueryBSONWithOptions,
+// e.g. a `_like` RegExp rejected by bsonbuilder.ValidateRegex or a malformed QueryKeyFilter.
+// Use QueryBSONWithOptions to report them.
 func (c *Base) QueryBSON(ctx *iris.Context, convertors ...func(key, value string) interface{}) bson.M {
+	query, err := c.QueryBSONWithOptions(ctx, QueryOptions{Convertors: convertors})
+	if err != nil {
+		// every document has the `_id`
+		return bson.M{"_id": bson.M{"$exists": false}}
+	}
+	return query
+}
+
+// QueryOptions customizes how QueryBSONWithOptions parses the query parameters.
+type QueryOptions struct {
+	// Schema whitelists the fields and operators, every field is permitted if it's nil.
+	Schema *QuerySchema
+	// Convertors convert the values which can't be parsed as the built-in types.
+	Convertors []func(key, value string) interface{}
+	// TextSearch enables QueryKeySearch as the full text search, the collection should have a text index.
+	TextSearch bool
+	// GeoField is the 2dsphere indexed field queried by the geo parameters, which are parsed as the normal
+	// parameters if it's empty.
+	GeoField string
+	// LikeMode decides how the values of `_like` and `_ilike` are matched, they're regular expressions by default.
+	// The regular expressions are checked by bsonbuilder.ValidateRegex, use the literal modes to avoid them.
+	LikeMode bsonbuilder.LikeMode
+	// RegexOptions are the options of `_like` and `_ilike` regular expressions, e.g. "m".
+	RegexOptions string
+	// ExtendedOperators enables the extended suffixes and `_elemMatch.` on every field, otherwise they're only
+	// used on the fields declared by Schema, so that the fields like `account_type` are still queried as they are.
+	ExtendedOperators bool
+}
+
+// extended returns true if the extended suffix could be trimmed from key as actualKey, the field of the array
+// being matched is prefix.
+func (opts QueryOptions) extended(prefix, key, actualKey string) bool {
+	if opts.ExtendedOperators {
+		return true
+	}
+	if opts.Schema == nil {
+		return false
+	}
+	_, declared := opts.Schema.Fields[prefix+fieldName(actualKey)]
+	_, whole := opts.Schema.Fields[prefix+fieldName(key)]
+	return declared && !whole
+}
+
+// querySuffixes decides what operator should be used by the suffix of key.
+var querySuffixes = []struct {
+	suffix string
+	op     bsonbuilder.Operator
+}{
+	{"_gt", bsonbuilder.OperatorGt},
+	{"_gte", bsonbuilder.OperatorGte},
+	{"_lt", bsonbuilder.OperatorLt},
+	{"_lte", bsonbuilder.OperatorLte},
+	{"_ne", bsonbuilder.OperatorNe},
+	{"_like", bsonbuilder.OperatorLike},
+	{"_exists", bsonbuilder.OperatorExists},
+}
+
+// queryExtendedSuffixes are the suffixes used only if QueryOptions.extended permits.
+var queryExtendedSuffixes = []struct {
+	suffix string
+	op     bsonbuilder.Operator
+}{
+	{"_in", bsonbuilder.OperatorIn},
+	{"_nin", bsonbuilder.OperatorNin},
+	{"_ilike", bsonbuilder.OperatorILike},
+	{"_prefix", bsonbuilder.OperatorPrefix},
+	{"_size", bsonbuilder.OperatorSize},
+	{"_all", bsonbuilder.OperatorAll},
+	{"_type", bsonbuilder.OperatorType},
+	{"_mod", bsonbuilder.OperatorMod},
+}
+
+// queryElemMatch separates the array field and the condition on its elements, e.g. `items_elemMatch.qty_gt`.
+const queryElemMatch = "_elemMatch."
+
+// QueryBSONWithOptions parses query parameter as bson.M like QueryBSON does with the options.
+// Besides the suffixes of QueryBSON, the extended ones are used if opts.ExtendedOperators is set
+// or the field is declared by opts.Schema:
+// - Add `_in`, `_nin` or `_all` with comma separated values, e.g. `status_in=open,closed`
+// - Add `_ilike` for case-insensitive RegExp, `_prefix` for the literal prefix
+// - Add `_size` to match the array length, `_type` to match the BSON type, `_mod` with `divisor,remainder`
+// - Use `_elemMatch.` to filter the array elements, e.g. `items_elemMatch.qty_gt=5`
+// - Use QueryKeySearch for the full text search if opts.TextSearch is set, e.g. `q=coffee`
+// - Use QueryKeyNear with QueryKeyMaxDistance or QueryKeyWithin to query opts.GeoField if it's set,
+// e.g. `near=-73.97,40.77&_maxDistance=500`
+// - Use QueryKeyFilter for the RSQL/FIQL filter which supports the logical groups,
+// e.g. `_filter=status==open,(priority=gt=3%3Bowner=exists=false)`, the filters with unescaped `;` are
+// reported by *ParamErrors
+//
+// If the schema is provided, the values are parsed as the declared types of the fields, and the parameters
+// on the unknown fields, with the forbidden operators or with the malformed values are dropped from the query
+// and reported by *ParamErrors, the error could be ignored to just skip them.
+func (c *Base) QueryBSONWithOptions(ctx *iris.Context, opts QueryOptions) (bson.M, error) {
 	var (
 		params = ctx.Request.URL.Query()
 		query  = bsonbuilder.New()
+		errs   []ParamError
+		mods   = make(map[string]bool)
 	)
 
+	keys := make([]string, 0, len(params))
 	for key := range params {
-		// skip pagination & sort keys
-		switch key {
-		case QueryKeyPage, QueryKeyLimit, QueryKeySort, QueryKeyOrder:
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// skip pagination, sort, search & geo keys
+		if opts.reserved(key) {
 			continue
 		}
 
 		for _, val := range params[key] {
-
-			// handle suffix _gt, _gte, _lt, _lte, _ne, _like, decides what operator should be used
-			actualKey, op := key, bsonbuilder.OperatorEq
-			for suffix, o := range map[string]bsonbuilder.Operator{
-				"_gt":     bsonbuilder.OperatorGt,
-				"_gte":    bsonbuilder.OperatorGte,
-				"_lt":     bsonbuilder.OperatorLt,
-				"_lte":    bsonbuilder.OperatorLte,
-				"_ne":     bsonbuilder.OperatorNe,
-				"_like":   bsonbuilder.OperatorLike,
-				"_exists": bsonbuilder.OperatorExists,
-			} {
-				if strings.HasSuffix(actualKey, suffix) {
-					op = o
-					actualKey = strings.TrimSuffix(actualKey, suffix)
-					break
-				}
+			field, op, vals, paramErr := parseQueryParam(opts, key, key, "", val)
+			if paramErr != nil {
+				errs = append(errs, *paramErr)
+				continue
 			}
-			switch {
-			// "id" => "_id"
-			case actualKey == "id":
-				if bson.IsObjectIdHex(val) {
-					query.Add("_id", op, bson.ObjectIdHex(val))
+			// the values of repeated `_mod` would be mixed up
+			if op == bsonbuilder.OperatorMod {
+				if mods[field] {
+					errs = append(errs, ParamError{Key: key, Value: val, Reason: "expect a single divisor,remainder"})
 					continue
 				}
+				mods[field] = true
+			}
+			query.Add(field, op, vals...)
+		}
+	}
 
-			// "user.id" => "user_id"
-			case strings.Contains(actualKey, ".id"):
-				if bson.IsObjectIdHex(val) {
-					actualKey = strings.Replace(actualKey, ".id", "._id", -1)
-					query.Add(actualKey, op, bson.ObjectIdHex(val))
-					continue
-				}
+	errs = append(errs, droppedFilters(ctx.Request.URL.RawQuery)...)
+	for _, filter := range params[QueryKeyFilter] {
+		sub, paramErr := parseFilter(opts, filter)
+		if paramErr != nil {
+			errs = append(errs, *paramErr)
+			continue
+		}
+		query.And(sub)
+	}
+
+	if search := params.Get(QueryKeySearch); search != "" && opts.TextSearch {
+		query.Add(bsonbuilder.KeyText, bsonbuilder.OperatorText, search)
+	}
+
+	if opts.GeoField != "" {
+		errs = append(errs, addGeoQuery(query, opts.GeoField, params)...)
+	}
+
+	if len(errs) > 0 {
+		return query.ToBSON(), &ParamErrors{Errors: errs}
+	}
+	return query.ToBSON(), nil
+}
+
+// addGeoQuery adds the geo parameters on field to query.
+func addGeoQuery(query bsonbuilder.Query, field string, params url.Values) (errs []ParamError) {
+	if near := params.Get(QueryKeyNear); near != "" {
+		coords, paramErr := parseCoordinates(QueryKeyNear, near)
+		if paramErr == nil && len(coords) != 2 {
+			paramErr = &ParamError{Key: QueryKeyNear, Value: near, Reason: "expect lng,lat"}
+		}
+
+		if maxDistance := params.Get(QueryKeyMaxDistance); maxDistance != "" && paramErr == nil {
+			distance, err := parseFloat(maxDistance)
+			if err != nil {
+				paramErr = &ParamError{Key: QueryKeyMaxDistance, Value: maxDistance, Reason: reason(err)}
 			}
+			coords = append(coords, distance)
+		}
 
-			// try to convert value to int
-			if i, err := strconv.ParseInt(val, 10, 0); err == nil {
-				query.Add(actualKey, op, int(i))
-				continue
+		if paramErr != nil {
+			errs = append(errs, *paramErr)
+		} else {
+			query.Add(field, bsonbuilder.OperatorNear, coords...)
+		}
+	}
+
+	if within := params.Get(QueryKeyWithin); within != "" {
+		coords, paramErr := parseCoordinates(QueryKeyWithin, within)
+		if paramErr == nil && bsonbuilder.OperatorGeoWithin.ToBSON(coords...) == nil {
+			paramErr = &ParamError{Key: QueryKeyWithin, Value: within, Reason: "expect a circle, box or polygon"}
+		}
+
+		if paramErr != nil {
+			errs = append(errs, *paramErr)
+		} else {
+			query.Add(field, bsonbuilder.OperatorGeoWithin, coords...)
+		}
+	}
+	return
+}
+
+// parseQueryParam returns the field, operator and values of the query parameter `param`, the key is the part of
+// param being parsed and the prefix is the field of the array whose elements are matched by key.
+func parseQueryParam(opts QueryOptions, param, key, prefix, val string) (string, bsonbuilder.Operator, []interface{}, *ParamError) {
+	if idx := strings.Index(key, queryElemMatch); idx > 0 && opts.extended(prefix, key, key[:idx]) {
+		arrayKey := key[:idx]
+		arrayField := fieldName(arrayKey)
+		if opts.Schema != nil {
+			if _, paramErr := opts.Schema.check(param, prefix+arrayField, bsonbuilder.OperatorElemMatch, ""); paramErr != nil {
+				paramErr.Value = val
+				return "", 0, nil, paramErr
 			}
+		}
 
-			// try to convert value to float64
-			if f64, err := strconv.ParseFloat(val, 64); err == nil {
-				query.Add(actualKey, op, f64)
-				continue
+		field, op, vals, paramErr := parseQueryParam(opts, param, key[idx+len(queryElemMatch):], prefix+arrayField+".", val)
+		if paramErr != nil {
+			return "", 0, nil, paramErr
+		}
+		return arrayField, bsonbuilder.OperatorElemMatch, []interface{}{bson.M{field: op.ToBSON(vals...)}}, nil
+	}
+
+	// handle suffixes, decides what operator should be used
+	actualKey, op := key, bsonbuilder.OperatorEq
+	for _, s := range querySuffixes {
+		if strings.HasSuffix(actualKey, s.suffix) {
+			op = s.op
+			actualKey = strings.TrimSuffix(actualKey, s.suffix)
+			break
+		}
+	}
+	if op == bsonbuilder.OperatorEq {
+		for _, s := range queryExtendedSuffixes {
+			if trimmed := strings.TrimSuffix(key, s.suffix); trimmed != key && opts.extended(prefix, key, trimmed) {
+				op = s.op
+				actualKey = trimmed
+				break
 			}
+		}
+	}
 
-			// try to convert value to boolean
-			if b, err := strconv.ParseBool(val); err == nil {
-				query.Add(actualKey, op, b)
+	raws := []string{val}
+	switch op {
+	case bsonbuilder.OperatorIn, bsonbuilder.OperatorNin, bsonbuilder.OperatorAll, bsonbuilder.OperatorMod:
+		raws = strings.Split(val, ",")
+	}
+
+	field, vals, paramErr := parseQueryValues(opts, param, actualKey, prefix, op, raws)
+	if paramErr != nil {
+		paramErr.Value = val
+		return "", 0, nil, paramErr
+	}
+	return field, op, vals, nil
+}
+
+// parseQueryValues returns the field of key and the values parsed from raws, which are checked by the schema if any.
+func parseQueryValues(opts QueryOptions, param, actualKey, prefix string, op bsonbuilder.Operator, raws []string) (string, []interface{}, *ParamError) {
+	if op == bsonbuilder.OperatorMod && len(raws) != 2 {
+		return "", nil, &ParamError{Key: param, Value: strings.Join(raws, ","), Reason: "expect divisor,remainder"}
+	}
+
+	if opts.Schema != nil {
+		field := fieldName(actualKey)
+		vals := make([]interface{}, len(raws))
+		for i, raw := range raws {
+			res, paramErr := opts.Schema.check(param, prefix+field, op, raw)
+			if paramErr != nil {
+				return "", nil, paramErr
+			}
+			if res == nil {
+				res = guessValue(field, raw, opts.Convertors)
+			}
+			vals[i] = res
+		}
+		if op == bsonbuilder.OperatorLike || op == bsonbuilder.OperatorILike {
+			vals, paramErr := likeValues(opts, param, raws)
+			return field, vals, paramErr
+		}
+		return field, vals, nil
+	}
+
+	if op == bsonbuilder.OperatorLike || op == bsonbuilder.OperatorILike {
+		vals, paramErr := likeValues(opts, param, raws)
+		return actualKey, vals, paramErr
+	}
+
+	vals := make([]interface{}, len(raws))
+	for i, raw := range raws {
+		switch {
+		// "id" => "_id"
+		case actualKey == "id":
+			if bson.IsObjectIdHex(raw) {
+				actualKey = "_id"
+				vals[i] = bson.ObjectIdHex(raw)
 				continue
 			}
 
-			// try to convert value to time.Time
-			if date, err := time.Parse(QueryFormatDateTime, val); err == nil {
-				query.Add(actualKey, op, date)
+		// "user.id" => "user_id"
+		case strings.Contains(actualKey, ".id"):
+			if bson.IsObjectIdHex(raw) {
+				actualKey = strings.Replace(actualKey, ".id", "._id", -1)
+				vals[i] = bson.ObjectIdHex(raw)
 				continue
 			}
 
-			// try to convert value using custom convertor(s)
-			skip := false
-			for _, c := range convertors {
-				if res := c(actualKey, val); res != nil {
-					query.Add(actualKey, op, res)
-					skip = true
-					break
-				}
+		case actualKey == "_id" || strings.Contains(actualKey, "._id"):
+			if bson.IsObjectIdHex(raw) {
+				vals[i] = bson.ObjectIdHex(raw)
+				continue
 			}
+		}
 
-			if !skip {
-				query.Add(actualKey, op, val)
+		vals[i] = guessValue(actualKey, raw, opts.Convertors)
+	}
+	return actualKey, vals, nil
+}
+
+// likeValues returns the bsonbuilder.Like values matched by opts.LikeMode,
+// the regular expressions are rejected if they're too long or too complex.
+func likeValues(opts QueryOptions, param string, raws []string) ([]interface{}, *ParamError) {
+	vals := make([]interface{}, len(raws))
+	for i, raw := range raws {
+		if opts.LikeMode == bsonbuilder.LikeRegex {
+			if err := bsonbuilder.ValidateRegex(raw); err != nil {
+				return nil, &ParamError{Key: param, Value: raw, Reason: err.Error()}
 			}
 		}
+		vals[i] = bsonbuilder.Like{Pattern: raw, Mode: opts.LikeMode, Options: opts.RegexOptions}
+	}
+	return vals, nil
+}
 
+// parseCoordinates parses the comma separated numbers.
+func parseCoordinates(key, val string) ([]interface{}, *ParamError) {
+	strs := strings.Split(val, ",")
+	coords := make([]interface{}, len(strs))
+	for i, str := range strs {
+		f, err := parseFloat(strings.TrimSpace(str))
+		if err != nil {
+			return nil, &ParamError{Key: key, Value: val, Reason: reason(err)}
+		}
+		coords[i] = f
+	}
+	return coords, nil
+}
+
+// fieldName maps the `id` segments of key to `_id`, e.g. `user.id` => `user._id`.
+func fieldName(key string) string {
+	segments := strings.Split(key, ".")
+	for i, segment := range segments {
+		if segment == "id" {
+			segments[i] = "_id"
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// guessValue tries to convert value to int, float64, boolean, time.Time and using custom convertor(s) in order,
+// returns the value itself if all of them failed.
+func guessValue(key, val string, convertors []func(key, value string) interface{}) interface{} {
+	// try to convert value to int
+	if i, err := strconv.ParseInt(val, 10, 0); err == nil {
+		return int(i)
+	}
+
+	// try to convert value to float64
+	if f64, err := strconv.ParseFloat(val, 64); err == nil {
+		return f64
+	}
+
+	// try to convert value to boolean
+	if b, err := strconv.ParseBool(val); err == nil {
+		return b
+	}
+
+	// try to convert value to time.Time
+	if date, err := time.Parse(QueryFormatDateTime, val); err == nil {
+		return date
+	}
+
+	// try to convert value using custom convertor(s)
+	for _, c := range convertors {
+		if res := c(key, val); res != nil {
+			return res
+		}
 	}
 
-	return query.ToBSON()
+	return val
 }
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/sy264115809/golem/controllers"
+	"github.com/sy264115809/golem/models/mgobase"
+	"github.com/sy264115809/golem/utils/bsonbuilder"
 
 	"github.com/stretchr/testify/assert"
 	iris "gopkg.in/kataras/iris.v6"
@@ -1246,6 +1248,80 @@ func TestParseQueryPagination(t *testing.T) {
 	controllers.QueryKeyLimit = defaultSettings[3].(string)
 }
 
+func TestParseQueryMarker(t *testing.T) {
+	type testcase struct {
+		q             string
+		expectedQuery interface{}
+		expectedSort  []string
+		expectedErr   []controllers.ParamError
+	}
+
+	cursor, err := mgobase.EncodeCursor([]interface{}{18, "jack"})
+	assert.NoError(t, err)
+
+	testcases := []testcase{
+		{
+			q:            "",
+			expectedSort: []string{"-age", "name"},
+		},
+		{
+			q:            "_page=last",
+			expectedSort: []string{"age", "-name"},
+		},
+		{
+			q: "_cursor=" + cursor,
+			expectedQuery: bson.M{"$or": []interface{}{
+				bson.M{"age": bson.M{"$lt": 18}},
+				bson.M{"age": 18, "name": bson.M{"$gt": "jack"}},
+			}},
+			expectedSort: []string{"-age", "name"},
+		},
+		{
+			q: "_page=prev&_cursor=" + cursor,
+			expectedQuery: bson.M{"$or": []interface{}{
+				bson.M{"age": bson.M{"$gt": 18}},
+				bson.M{"age": 18, "name": bson.M{"$lt": "jack"}},
+			}},
+			expectedSort: []string{"age", "-name"},
+		},
+		{
+			q:           "_page=3",
+			expectedErr: []controllers.ParamError{{Key: "_page", Value: "3", Reason: "expect first, last, next or prev"}},
+		},
+		{
+			q:           "_page=next",
+			expectedErr: []controllers.ParamError{{Key: "_cursor", Reason: "cursor is required for the next page"}},
+		},
+		{
+			q:           "_cursor=abc",
+			expectedErr: []controllers.ParamError{{Key: "_cursor", Value: "abc", Reason: "invalid cursor"}},
+		},
+	}
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/marker/:case", func(ctx *iris.Context) {
+		idx, err := ctx.ParamInt("case")
+		assert.NoError(t, err)
+
+		tc := testcases[idx]
+		marker, err := baseController().Marker(ctx, "-age,name")
+		if tc.expectedErr != nil {
+			assert.IsType(t, &controllers.ParamErrors{}, err)
+			assert.Equal(t, tc.expectedErr, err.(*controllers.ParamErrors).Errors)
+			return
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectedQuery, marker.QueryStatement(nil))
+		assert.Equal(t, tc.expectedSort, marker.SortFields())
+	})
+
+	for i, tc := range testcases {
+		httptest.New(app, t).GET(fmt.Sprintf("/marker/%d", i)).WithQueryString(tc.q).Expect()
+	}
+}
+
 func TestParseQuerySort(t *testing.T) {
 	type testcase struct {
 		sort     string
@@ -1429,6 +1505,15 @@ func TestParseQuerySortAsMultiple(t *testing.T) {
 			sort:     "field,-field2,-field3",
 			expected: []string{"field", "-field2", "-field3"},
 		},
+		{
+			sort:     "_score,-field",
+			expected: []string{"$textScore:score", "-field"},
+		},
+		{
+			sort:     "_score",
+			order:    "desc",
+			expected: []string{"$textScore:score"},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -1442,12 +1527,16 @@ func TestParseQuerySortAsMultiple(t *testing.T) {
 			res.Null()
 		}
 	}
+
+	httptest.New(app, t).GET("/sort-multiple").WithQuery("q", "coffee").
+		Expect().Status(iris.StatusOK).JSON().Array().Equal([]string{"$textScore:score"})
 }
 
 func TestQueryBSON(t *testing.T) {
 	type testcase struct {
 		q        string
 		expected bson.M
+		errs     []controllers.ParamError
 	}
 
 	testFn := func(t *testing.T, testcases []testcase) {
@@ -1467,6 +1556,28 @@ func TestQueryBSON(t *testing.T) {
 		}
 	}
 
+	testWithOptionsFn := func(t *testing.T, opts controllers.QueryOptions, testcases []testcase) {
+		app := iris.New()
+		app.Adapt(httprouter.New())
+		app.Get("/query-bson/:case", func(ctx *iris.Context) {
+			idx, err := ctx.ParamInt("case")
+			assert.NoError(t, err)
+
+			tc := testcases[idx]
+			actual, err := baseController().QueryBSONWithOptions(ctx, opts)
+			assert.Equal(t, tc.expected, actual, tc.q)
+			if tc.errs == nil {
+				assert.NoError(t, err, tc.q)
+			} else if assert.IsType(t, &controllers.ParamErrors{}, err, tc.q) {
+				assert.Equal(t, tc.errs, err.(*controllers.ParamErrors).Errors, tc.q)
+			}
+		})
+
+		for i, tc := range testcases {
+			httptest.New(app, t).GET(fmt.Sprintf("/query-bson/%d", i)).WithQueryString(tc.q).Expect()
+		}
+	}
+
 	t.Run("normal query", func(t *testing.T) {
 		testcases := []testcase{
 			{
@@ -1627,6 +1738,222 @@ func TestQueryBSON(t *testing.T) {
 		testFn(t, testcases)
 	})
 
+	t.Run("query with extended operator", func(t *testing.T) {
+		testcases := []testcase{
+			{
+				q: "status_in=open,closed,3",
+				expected: bson.M{
+					"status": bson.M{"$in": []interface{}{"open", "closed", 3}},
+				},
+			},
+			{
+				q: "status_nin=open",
+				expected: bson.M{
+					"status": bson.M{"$nin": []interface{}{"open"}},
+				},
+			},
+			{
+				q: "id_in=58db2700cf2f6715b00021a7,58db2700cf2f6715b00021a8",
+				expected: bson.M{
+					"_id": bson.M{"$in": []interface{}{bson.ObjectIdHex("58db2700cf2f6715b00021a7"), bson.ObjectIdHex("58db2700cf2f6715b00021a8")}},
+				},
+			},
+			{
+				q: "name_ilike=Black",
+				expected: bson.M{
+					"name": bson.M{"$regex": bson.RegEx{Pattern: "Black", Options: "i"}},
+				},
+			},
+			{
+				q: "email_prefix=jack.b",
+				expected: bson.M{
+					"email": bson.M{"$regex": bson.RegEx{Pattern: `^jack\.b`}},
+				},
+			},
+			{
+				q: "tags_size=2",
+				expected: bson.M{
+					"tags": bson.M{"$size": 2},
+				},
+			},
+			{
+				q: "tags_all=go,mongo",
+				expected: bson.M{
+					"tags": bson.M{"$all": []interface{}{"go", "mongo"}},
+				},
+			},
+			{
+				q: "items_elemMatch.qty_gt=5&items_elemMatch.sku=abc",
+				expected: bson.M{
+					"items": bson.M{"$elemMatch": bson.M{"qty": bson.M{"$gt": 5}, "sku": bson.M{"$eq": "abc"}}},
+				},
+			},
+			{
+				q: "zip_type=string",
+				expected: bson.M{
+					"zip": bson.M{"$type": "string"},
+				},
+			},
+			{
+				q: "qty_mod=4,0",
+				expected: bson.M{
+					"qty": bson.M{"$mod": []interface{}{4, 0}},
+				},
+			},
+			{
+				q:        "qty_mod=4",
+				expected: bson.M{},
+				errs:     []controllers.ParamError{{Key: "qty_mod", Value: "4", Reason: "expect divisor,remainder"}},
+			},
+			{
+				q:        "qty_mod=4,0,2,1",
+				expected: bson.M{},
+				errs:     []controllers.ParamError{{Key: "qty_mod", Value: "4,0,2,1", Reason: "expect divisor,remainder"}},
+			},
+			{
+				q: "qty_mod=4,0&qty_mod=2,1",
+				expected: bson.M{
+					"qty": bson.M{"$mod": []interface{}{4, 0}},
+				},
+				errs: []controllers.ParamError{{Key: "qty_mod", Value: "2,1", Reason: "expect a single divisor,remainder"}},
+			},
+		}
+		testWithOptionsFn(t, controllers.QueryOptions{ExtendedOperators: true}, testcases)
+	})
+
+	t.Run("query on fields ending with extended suffixes", func(t *testing.T) {
+		testcases := []testcase{
+			{
+				q: "account_type=premium&shoe_size=42&user_in=x&is_all=true",
+				expected: bson.M{
+					"account_type": bson.M{"$eq": "premium"},
+					"shoe_size":    bson.M{"$eq": 42},
+					"user_in":      bson.M{"$eq": "x"},
+					"is_all":       bson.M{"$eq": true},
+				},
+			},
+			{
+				q: "items_elemMatch.qty_gt=5",
+				expected: bson.M{
+					"items_elemMatch.qty": bson.M{"$gt": 5},
+				},
+			},
+		}
+		testWithOptionsFn(t, controllers.QueryOptions{}, testcases)
+	})
+
+	t.Run("search & geo query", func(t *testing.T) {
+		testcases := []testcase{
+			{
+				q: "q=coffee+shop&status=open",
+				expected: bson.M{
+					"$text":  bson.M{"$search": "coffee shop"},
+					"status": bson.M{"$eq": "open"},
+				},
+			},
+			{
+				q: "near=-73.97,40.77&_maxDistance=500",
+				expected: bson.M{
+					"location": bson.M{"$near": bson.M{
+						"$geometry":    bson.M{"type": "Point", "coordinates": []float64{-73.97, 40.77}},
+						"$maxDistance": 500.0,
+					}},
+				},
+			},
+			{
+				q: "within=0,0,100,100",
+				expected: bson.M{
+					"location": bson.M{"$geoWithin": bson.M{"$box": [][]float64{{0, 0}, {100, 100}}}},
+				},
+			},
+			{
+				q:        "near=-73.97&within=a,b",
+				expected: bson.M{},
+				errs: []controllers.ParamError{
+					{Key: "near", Value: "-73.97", Reason: "expect lng,lat"},
+					{Key: "within", Value: "a,b", Reason: "invalid syntax"},
+				},
+			},
+		}
+		testWithOptionsFn(t, controllers.QueryOptions{TextSearch: true, GeoField: "location"}, testcases)
+	})
+
+	t.Run("search & geo disabled", func(t *testing.T) {
+		testcases := []testcase{
+			{
+				q: "q=coffee&near=-73.97,40.77&within=a,b",
+				expected: bson.M{
+					"q":      bson.M{"$eq": "coffee"},
+					"near":   bson.M{"$eq": "-73.97,40.77"},
+					"within": bson.M{"$eq": "a,b"},
+				},
+			},
+		}
+		testWithOptionsFn(t, controllers.QueryOptions{}, testcases)
+	})
+
+	t.Run("filter query", func(t *testing.T) {
+		testcases := []testcase{
+			{
+				q: "_filter=status==open,priority=gt=3",
+				expected: bson.M{
+					"$and": []interface{}{
+						bson.M{"$or": []interface{}{
+							bson.M{"status": bson.M{"$eq": "open"}},
+							bson.M{"priority": bson.M{"$gt": 3}},
+						}},
+					},
+				},
+			},
+			{
+				q: "_filter=status==open%3B(priority>=3,owner=exists=false)%3Btags=in=(a,'b,c')&age=1",
+				expected: bson.M{
+					"age": bson.M{"$eq": 1},
+					"$and": []interface{}{
+						bson.M{
+							"status": bson.M{"$eq": "open"},
+							"tags":   bson.M{"$in": []interface{}{"a", "b,c"}},
+							"$or": []interface{}{
+								bson.M{"priority": bson.M{"$gte": 3}},
+								bson.M{"owner": bson.M{"$exists": false}},
+							},
+						},
+					},
+				},
+			},
+			{
+				q: "_filter=age>1%3Bage<9%3Bage>2",
+				expected: bson.M{
+					"$and": []interface{}{
+						bson.M{
+							"age":  bson.M{"$gt": 1, "$lt": 9},
+							"$and": []interface{}{bson.M{"age": bson.M{"$gt": 2}}},
+						},
+					},
+				},
+			},
+			{
+				q: "_filter=id==58db2700cf2f6715b00021a7",
+				expected: bson.M{
+					"$and": []interface{}{
+						bson.M{"_id": bson.M{"$eq": bson.ObjectIdHex("58db2700cf2f6715b00021a7")}},
+					},
+				},
+			},
+			{
+				q:        "_filter=status=open",
+				expected: bson.M{},
+				errs:     []controllers.ParamError{{Key: "_filter", Value: "status=open", Reason: `syntax error at position 6, unknown comparator at "=open"`}},
+			},
+			{
+				q:        "_filter=(a==1",
+				expected: bson.M{},
+				errs:     []controllers.ParamError{{Key: "_filter", Value: "(a==1", Reason: "syntax error at position 5, missing ')'"}},
+			},
+		}
+		testWithOptionsFn(t, controllers.QueryOptions{}, testcases)
+	})
+
 	t.Run("complex query", func(t *testing.T) {
 		testcases := []testcase{
 			{
@@ -1644,3 +1971,83 @@ func TestQueryBSON(t *testing.T) {
 		testFn(t, testcases)
 	})
 }
+
+func TestQueryBSONWithInvalidParams(t *testing.T) {
+	var actual bson.M
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/query-bson", func(ctx *iris.Context) {
+		actual = baseController().QueryBSON(ctx)
+	})
+
+	nothing := bson.M{"_id": bson.M{"$exists": false}}
+	for _, q := range []string{
+		"name_like=(a%2B)%2B&age=1",
+		"_filter=(a==1&age=1",
+		"_filter=status==open;age>1&age=1",
+	} {
+		httptest.New(app, t).GET("/query-bson?" + q).Expect()
+		assert.Equal(t, nothing, actual, q)
+	}
+}
+
+func TestQueryBSONWithLikeOptions(t *testing.T) {
+	var (
+		opts   controllers.QueryOptions
+		actual bson.M
+		err    error
+	)
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/query-bson", func(ctx *iris.Context) {
+		actual, err = baseController().QueryBSONWithOptions(ctx, opts)
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		opts = controllers.QueryOptions{RegexOptions: "m", ExtendedOperators: true}
+		httptest.New(app, t).GET("/query-bson").WithQueryString("name_like=^ja.k&email_ilike=@gmail&bio_like=(a%2B)%2B").Expect()
+
+		assert.Equal(t, bson.M{
+			"name":  bson.M{"$regex": bson.RegEx{Pattern: "^ja.k", Options: "m"}},
+			"email": bson.M{"$regex": bson.RegEx{Pattern: "@gmail", Options: "im"}},
+		}, actual)
+		assert.Equal(t, []controllers.ParamError{
+			{Key: "bio_like", Value: "(a+)+", Reason: "regex is too complex"},
+		}, err.(*controllers.ParamErrors).Errors)
+	})
+
+	t.Run("literal", func(t *testing.T) {
+		opts = controllers.QueryOptions{LikeMode: bsonbuilder.LikeContains, ExtendedOperators: true}
+		httptest.New(app, t).GET("/query-bson").WithQueryString("name_like=ja.k&email_ilike=@gmail&bio_like=(a%2B)%2B").Expect()
+
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{
+			"name":  bson.M{"$regex": bson.RegEx{Pattern: `ja\.k`}},
+			"email": bson.M{"$regex": bson.RegEx{Pattern: "@gmail", Options: "i"}},
+			"bio":   bson.M{"$regex": bson.RegEx{Pattern: `\(a\+\)\+`}},
+		}, actual)
+	})
+}
+
+func TestQueryBSONWithDroppedFilter(t *testing.T) {
+	var (
+		actual bson.M
+		err    error
+	)
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/query-bson", func(ctx *iris.Context) {
+		actual, err = baseController().QueryBSONWithOptions(ctx, controllers.QueryOptions{})
+	})
+
+	// the raw query is kept as it is, since WithQueryString parses it
+	httptest.New(app, t).GET("/query-bson?_filter=status==open;age>1&age=1").Expect()
+
+	assert.Equal(t, bson.M{"age": bson.M{"$eq": 1}}, actual)
+	assert.Equal(t, []controllers.ParamError{
+		{Key: "_filter", Value: "status==open;age>1", Reason: "`;` must be escaped as %3B"},
+	}, err.(*controllers.ParamErrors).Errors)
+}
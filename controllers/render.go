@@ -0,0 +1,316 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack"
+	iris "gopkg.in/kataras/iris.v6"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The media types of built-in renderers.
+const (
+	MIMEJSON        = "application/json"
+	MIMEXML         = "application/xml"
+	MIMEXMLText     = "text/xml"
+	MIMEYAML        = "application/x-yaml"
+	MIMEYAMLText    = "text/yaml"
+	MIMEMessagePack = "application/x-msgpack"
+	MIMEText        = "text/plain"
+)
+
+// Renderer renders the response data into the body with given http status.
+type Renderer func(ctx *iris.Context, status int, data iris.Map) error
+
+type renderEntry struct {
+	mediaType string
+	renderer  Renderer
+}
+
+var (
+	renderersLock sync.RWMutex
+	renderers     = []renderEntry{
+		{MIMEJSON, renderJSON},
+		{MIMEXML, renderXML},
+		{MIMEXMLText, renderXML},
+		{MIMEYAML, renderYAML},
+		{MIMEYAMLText, renderYAML},
+		{MIMEMessagePack, renderMessagePack},
+		{MIMEText, renderText},
+	}
+
+	// DefaultMediaType is the media type used when the request doesn't specify the Accept header
+	// or none of the acceptable media types could be rendered.
+	DefaultMediaType = MIMEJSON
+)
+
+// RegisterRenderer registers a renderer for the media type, the existing one will be replaced.
+func RegisterRenderer(mediaType string, renderer Renderer) {
+	renderersLock.Lock()
+	defer renderersLock.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	for i, entry := range renderers {
+		if entry.mediaType == mediaType {
+			renderers[i].renderer = renderer
+			return
+		}
+	}
+	renderers = append(renderers, renderEntry{mediaType, renderer})
+}
+
+// Negotiate returns the media type and the renderer which best match the `accept` header.
+// Only the media ranges with the highest quality are weighed, the DefaultMediaType is used if none of them
+// could be rendered, e.g. the browsers preferring `text/html` get JSON rather than their fallback XML.
+func Negotiate(accept string) (string, Renderer) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+
+	ranges := parseAccept(accept)
+	for _, mediaRange := range ranges {
+		if mediaRange.quality < ranges[0].quality {
+			break
+		}
+		if mediaRange.value == "*/*" {
+			continue
+		}
+		for _, entry := range renderers {
+			if matchMediaRange(mediaRange.value, entry.mediaType) {
+				return entry.mediaType, entry.renderer
+			}
+		}
+	}
+
+	for _, entry := range renderers {
+		if entry.mediaType == DefaultMediaType {
+			return entry.mediaType, entry.renderer
+		}
+	}
+	return MIMEJSON, renderJSON
+}
+
+type mediaRange struct {
+	value   string
+	quality float64
+}
+
+// parseAccept returns the media ranges of Accept header sorted by quality in descending order,
+// the media ranges with zero quality are dropped.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{value, quality})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+func matchMediaRange(mediaRange, mediaType string) bool {
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return mediaRange == mediaType
+}
+
+func renderJSON(ctx *iris.Context, status int, data iris.Map) error {
+	return ctx.JSON(status, data)
+}
+
+// jsonData converts data into its JSON representation, so that the other formats respect the `json` tags
+// and the json.Marshaler of the nested values like JSON does.
+func jsonData(data iris.Map) (map[string]interface{}, error) {
+	byts, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(byts))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	jsonNumbers(m)
+	return m, nil
+}
+
+// jsonNumbers replaces the json.Number in v with int64 or float64 in place.
+func jsonNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range val {
+			val[k] = jsonNumbers(elem)
+		}
+	case []interface{}:
+		for i, elem := range val {
+			val[i] = jsonNumbers(elem)
+		}
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	}
+	return v
+}
+
+func renderXML(ctx *iris.Context, status int, data iris.Map) error {
+	m, err := jsonData(data)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXML(enc, "response", reflect.ValueOf(m)); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	return write(ctx, status, MIMEXML+"; charset=utf-8", buf.Bytes())
+}
+
+// encodeXML encodes maps into elements named by their keys, and the elements of slices into `item` elements,
+// since encoding/xml can't marshal maps.
+func encodeXML(enc *xml.Encoder, name string, v reflect.Value) error {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return enc.EncodeElement("", xml.StartElement{Name: xml.Name{Local: name}})
+		}
+		if _, ok := v.Interface().(xml.Marshaler); ok {
+			break
+		}
+		v = v.Elem()
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, key := range keys {
+			if err := encodeXML(enc, key.String(), v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeXML(enc, "item", v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	}
+
+	return enc.EncodeElement(v.Interface(), start)
+}
+
+func renderYAML(ctx *iris.Context, status int, data iris.Map) error {
+	m, err := jsonData(data)
+	if err != nil {
+		return err
+	}
+
+	byts, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return write(ctx, status, MIMEYAML+"; charset=utf-8", byts)
+}
+
+func renderMessagePack(ctx *iris.Context, status int, data iris.Map) error {
+	m, err := jsonData(data)
+	if err != nil {
+		return err
+	}
+
+	byts, err := msgpack.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return write(ctx, status, MIMEMessagePack, byts)
+}
+
+// renderText renders the message if it's the only data, or the sorted key: value lines.
+func renderText(ctx *iris.Context, status int, data iris.Map) error {
+	var buf bytes.Buffer
+	if message, ok := data["message"].(string); ok && len(data) == 1 {
+		buf.WriteString(message)
+	} else {
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fmt.Fprintf(&buf, "%s: %s\n", key, textValue(data[key]))
+		}
+	}
+	return write(ctx, status, MIMEText+"; charset=utf-8", buf.Bytes())
+}
+
+func textValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case fmt.Stringer:
+		return val.String()
+	}
+
+	if byts, err := json.Marshal(v); err == nil {
+		return string(byts)
+	}
+	return fmt.Sprint(v)
+}
+
+func write(ctx *iris.Context, status int, contentType string, body []byte) error {
+	ctx.SetHeader("Content-Type", contentType)
+	ctx.WriteHeader(status)
+	_, err := ctx.Write(body)
+	return err
+}
@@ -0,0 +1,104 @@
+package controllers_test
+
+import (
+	"testing"
+
+	"github.com/sy264115809/golem/controllers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack"
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func TestNegotiate(t *testing.T) {
+	type testcase struct {
+		accept   string
+		expected string
+	}
+
+	testcases := []testcase{
+		{accept: "", expected: controllers.MIMEJSON},
+		{accept: "*/*", expected: controllers.MIMEJSON},
+		{accept: "application/xml", expected: controllers.MIMEXML},
+		{accept: "text/xml, application/json;q=0.9", expected: controllers.MIMEXMLText},
+		{accept: "application/json;q=0.5, application/x-yaml", expected: controllers.MIMEYAML},
+		{accept: "application/x-msgpack", expected: controllers.MIMEMessagePack},
+		{accept: "text/*", expected: controllers.MIMEXMLText},
+		{accept: "text/plain;q=0, text/html", expected: controllers.MIMEJSON},
+		{accept: "image/png", expected: controllers.MIMEJSON},
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: controllers.MIMEJSON},
+		{accept: "text/html, application/x-yaml", expected: controllers.MIMEYAML},
+	}
+
+	for _, tc := range testcases {
+		mediaType, renderer := controllers.Negotiate(tc.accept)
+		assert.Equal(t, tc.expected, mediaType, tc.accept)
+		assert.NotNil(t, renderer, tc.accept)
+	}
+
+	t.Run("custom renderer", func(t *testing.T) {
+		defer controllers.SnapshotRenderers()()
+
+		controllers.RegisterRenderer("application/vnd.custom", func(ctx *iris.Context, status int, data iris.Map) error {
+			return nil
+		})
+
+		mediaType, _ := controllers.Negotiate("application/vnd.custom, */*;q=0.1")
+		assert.Equal(t, "application/vnd.custom", mediaType)
+	})
+
+	mediaType, _ := controllers.Negotiate("application/vnd.custom, */*;q=0.1")
+	assert.Equal(t, controllers.MIMEJSON, mediaType)
+}
+
+func TestRenderNestedStructs(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		Name    string  `json:"name"`
+		Age     int     `json:"age"`
+		Address address `json:"address"`
+		Secret  string  `json:"-"`
+	}
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/render", func(ctx *iris.Context) {
+		_, renderer := controllers.Negotiate(ctx.RequestHeader("Accept"))
+		renderer(ctx, iris.StatusOK, iris.Map{
+			"user": user{Name: "jack", Age: 18, Address: address{City: "NY"}, Secret: "secret"},
+		})
+	})
+
+	e := httptest.New(app, t)
+	e.GET("/render").WithHeader("Accept", controllers.MIMEJSON).Expect().JSON().Object().Equal(map[string]interface{}{
+		"user": map[string]interface{}{"name": "jack", "age": 18, "address": map[string]interface{}{"city": "NY"}},
+	})
+
+	e.GET("/render").WithHeader("Accept", controllers.MIMEXML).Expect().Body().Equal(
+		`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<response><user><address><city>NY</city></address><age>18</age><name>jack</name></user></response>`)
+
+	e.GET("/render").WithHeader("Accept", controllers.MIMEYAML).Expect().Body().Equal(
+		"user:\n  address:\n    city: NY\n  age: 18\n  name: jack\n")
+
+	var actual struct {
+		User struct {
+			Name    string `msgpack:"name"`
+			Age     int    `msgpack:"age"`
+			Address struct {
+				City string `msgpack:"city"`
+			} `msgpack:"address"`
+			Secret string `msgpack:"Secret"`
+		} `msgpack:"user"`
+	}
+	body := e.GET("/render").WithHeader("Accept", controllers.MIMEMessagePack).Expect().Body().Raw()
+	assert.NoError(t, msgpack.Unmarshal([]byte(body), &actual))
+	assert.Equal(t, "jack", actual.User.Name)
+	assert.Equal(t, 18, actual.User.Age)
+	assert.Equal(t, "NY", actual.User.Address.City)
+	assert.Empty(t, actual.User.Secret)
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/fatih/structs"
+	"github.com/sy264115809/golem/models/mgobase"
+	"github.com/sy264115809/logrush"
 
 	iris "gopkg.in/kataras/iris.v6"
 )
@@ -13,9 +17,10 @@ import (
 type (
 	// Response can make a response with given datas and status.
 	Response struct {
-		ctx  *iris.Context
-		data iris.Map
-		code Code
+		ctx    *iris.Context
+		data   iris.Map
+		code   Code
+		logger *logrush.Logger
 	}
 
 	// Code could be a user defined number with specific meaning.
@@ -25,14 +30,14 @@ type (
 	}
 )
 
-// WithData sets the given key-value pair to the response json body.
+// WithData sets the given key-value pair to the response body.
 // It will overwrites the value with same key.
 func (r *Response) WithData(key string, value interface{}) *Response {
 	r.data[key] = value
 	return r
 }
 
-// WithDatas sets multiple key-value pairs to the response json body.
+// WithDatas sets multiple key-value pairs to the response body.
 // All values has the same key as the `datas` has will be overwrited.
 func (r *Response) WithDatas(datas iris.Map) *Response {
 	for k, v := range datas {
@@ -51,63 +56,201 @@ func (r *Response) WithStruct(v interface{}) *Response {
 	return r
 }
 
+var (
+	// ResponseKeyPrevCursor is the key of the previous page cursor in the response body, see Response.WithCursor.
+	ResponseKeyPrevCursor = "prev_cursor"
+	// ResponseKeyNextCursor is the key of the next page cursor in the response body, see Response.WithCursor.
+	ResponseKeyNextCursor = "next_cursor"
+)
+
+// WithCursor sets the cursors of the previous and next pages returned by marker pagination to the response body,
+// and the Link header (RFC 5988) of the first, previous, next and last pages built from the request url.
+// The nil prev or next is skipped, so is the prev of the first page and the next of the last page requested,
+// see Base.Marker and mgobase.EncodeCursor also. The cursors failed to be encoded are logged and skipped.
+func (r *Response) WithCursor(prev, next interface{}) *Response {
+	links := []link{{rel: "first", params: url.Values{QueryKeyPage: {mgobase.PageFirst}}}}
+
+	page := strings.ToLower(r.ctx.URLParam(QueryKeyPage))
+	switch {
+	case page == mgobase.PageFirst || (page == "" && r.ctx.URLParam(QueryKeyCursor) == ""):
+		prev = nil
+	case page == mgobase.PageLast:
+		next = nil
+	}
+
+	if cursor := r.encodeCursor(prev); cursor != "" {
+		r.data[ResponseKeyPrevCursor] = cursor
+		links = append(links, link{rel: "prev", params: url.Values{QueryKeyCursor: {cursor}, QueryKeyPage: {mgobase.PagePrev}}})
+	}
+	if cursor := r.encodeCursor(next); cursor != "" {
+		r.data[ResponseKeyNextCursor] = cursor
+		links = append(links, link{rel: "next", params: url.Values{QueryKeyCursor: {cursor}, QueryKeyPage: {mgobase.PageNext}}})
+	}
+
+	links = append(links, link{rel: "last", params: url.Values{QueryKeyPage: {mgobase.PageLast}}})
+	r.setLinks(links, QueryKeyCursor)
+	return r
+}
+
+// encodeCursor returns the cursor of value, or an empty one if it fails to be encoded.
+func (r *Response) encodeCursor(value interface{}) string {
+	cursor, err := mgobase.EncodeCursor(value)
+	if err != nil && r.logger != nil {
+		r.logger.WithField("value", value).Error("failed to encode cursor: ", err)
+	}
+	return cursor
+}
+
+var (
+	// ResponseKeyPagination is the key of the pagination metadata in the response body, see Response.WithPaginater.
+	ResponseKeyPagination = "pagination"
+	// HeaderTotalCount is the header of the total number of items, see Response.WithPaginater.
+	HeaderTotalCount = "X-Total-Count"
+)
+
+// PageRange is the arguments of `Paginater.IterRange` to render the page items, see Response.WithPaginater.
+type PageRange struct {
+	LeftEdge     int
+	LeftCurrent  int
+	RightCurrent int
+	RightEdge    int
+}
+
+// WithPaginater sets the pagination metadata of p to the response body, e.g.
+//
+//	{"pagination": {"page": 2, "limit": 20, "total_items": 95, "total_pages": 5, "has_prev": true, "has_next": true}}
+//
+// and the HeaderTotalCount header and the Link header (RFC 5988) of the first, previous, next and last pages
+// built from the request url. The page items of IterRange are rendered as `items` if the page range is provided,
+// which is useful for the server-rendered pagers.
+func (r *Response) WithPaginater(p mgobase.Paginater, pageRange ...PageRange) *Response {
+	pagination := iris.Map{
+		"page":        p.Page(),
+		"limit":       p.Limit(),
+		"total_items": p.TotalItems(),
+		"total_pages": p.TotalPages(),
+		"has_prev":    p.HasPrev(),
+		"has_next":    p.HasNext(),
+	}
+	if len(pageRange) > 0 {
+		pr := pageRange[0]
+		items := make([]iris.Map, 0)
+		for _, item := range p.IterRange(pr.LeftEdge, pr.LeftCurrent, pr.RightCurrent, pr.RightEdge) {
+			items = append(items, iris.Map{"page": item.PageNum, "placeholder": item.IsPlaceHolder})
+		}
+		pagination["items"] = items
+	}
+	r.data[ResponseKeyPagination] = pagination
+
+	page := func(rel string, n int) link {
+		params := url.Values{QueryKeyPage: {strconv.Itoa(n)}}
+		if p.Limit() > 0 {
+			params.Set(QueryKeyLimit, strconv.Itoa(p.Limit()))
+		}
+		return link{rel: rel, params: params}
+	}
+	links := []link{page("first", 1)}
+	if p.HasPrev() {
+		links = append(links, page("prev", p.PrevPage()))
+	}
+	if p.HasNext() {
+		links = append(links, page("next", p.NextPage()))
+	}
+	links = append(links, page("last", p.TotalPages()))
+
+	r.ctx.SetHeader(HeaderTotalCount, strconv.Itoa(p.TotalItems()))
+	r.setLinks(links, QueryKeyCursor)
+	return r
+}
+
+// link is a link of the Link header.
+type link struct {
+	rel    string
+	params url.Values
+}
+
+// setLinks sets the Link header, the url of every link is the request url with its params,
+// the params of exclude are removed from the url.
+func (r *Response) setLinks(links []link, exclude ...string) {
+	values := make([]string, len(links))
+	for i, l := range links {
+		u := *r.ctx.Request.URL
+		q := u.Query()
+		for _, key := range exclude {
+			q.Del(key)
+		}
+		for key, vals := range l.params {
+			q[key] = vals
+		}
+		u.RawQuery = q.Encode()
+		values[i] = fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), l.rel)
+	}
+	r.ctx.SetHeader("Link", strings.Join(values, ", "))
+}
+
 // WithCode sets biz code of the response.
 func (r *Response) WithCode(code Code) *Response {
 	r.code = code
 	return r
 }
 
-// Ok render json format response with iris.OK and given message.
+// Ok render response with iris.OK and given message.
 func (r *Response) Ok(message ...string) {
-	r.json(iris.StatusOK, message...)
+	r.render(iris.StatusOK, message...)
 }
 
-// Created render json format response with iris.Created and given message.
+// Created render response with iris.Created and given message.
 func (r *Response) Created(message ...string) {
-	r.json(iris.StatusCreated, message...)
+	r.render(iris.StatusCreated, message...)
 }
 
-// BadRequest render json format response with iris.StatusBadRequest and given message.
+// BadRequest render response with iris.StatusBadRequest and given message.
 func (r *Response) BadRequest(message ...string) {
-	r.json(iris.StatusBadRequest, message...)
+	r.render(iris.StatusBadRequest, message...)
 }
 
-// Unauthorized render json format response with iris.StatusUnauthorized and given message.
+// Unauthorized render response with iris.StatusUnauthorized and given message.
 func (r *Response) Unauthorized(message ...string) {
-	r.json(iris.StatusUnauthorized, message...)
+	r.render(iris.StatusUnauthorized, message...)
 }
 
-// Forbidden render json format response with iris.StatusForbidden and given message.
+// Forbidden render response with iris.StatusForbidden and given message.
 func (r *Response) Forbidden(message ...string) {
-	r.json(iris.StatusForbidden, message...)
+	r.render(iris.StatusForbidden, message...)
 }
 
-// NotFound render json format response with iris.StatusNotFound and given message.
+// NotFound render response with iris.StatusNotFound and given message.
 func (r *Response) NotFound(message ...string) {
-	r.json(iris.StatusNotFound, message...)
+	r.render(iris.StatusNotFound, message...)
 }
 
-// Conflict render json format response with iris.StatusConflict and given message.
+// Conflict render response with iris.StatusConflict and given message.
 func (r *Response) Conflict(message ...string) {
-	r.json(iris.StatusConflict, message...)
+	r.render(iris.StatusConflict, message...)
 }
 
-// InternalServerError render json format response with iris.InternalServerError and given message.
+// InternalServerError render response with iris.InternalServerError and given message.
 func (r *Response) InternalServerError(message ...string) {
-	r.json(iris.StatusInternalServerError, message...)
+	r.render(iris.StatusInternalServerError, message...)
 }
 
-// CustomCode render json format response with given http code and message.
+// CustomCode render response with given http code and message.
 func (r *Response) CustomCode(code int, message ...string) {
-	r.json(code, message...)
+	r.render(code, message...)
 }
 
-func (r *Response) json(code int, message ...string) {
+// render renders the response in the format negotiated by the Accept header, see `Negotiate`.
+func (r *Response) render(code int, message ...string) {
 	if len(message) > 0 {
 		r.data["message"] = message[0]
 	}
 	r.parseCode()
-	r.ctx.JSON(code, r.data)
+
+	mediaType, renderer := Negotiate(r.ctx.RequestHeader("Accept"))
+	r.ctx.SetHeader("Vary", "Accept")
+	if err := renderer(r.ctx, code, r.data); err != nil && r.logger != nil {
+		r.logger.WithField("media_type", mediaType).Error("failed to render response: ", err)
+	}
 }
 
 func (r *Response) parseCode() {
@@ -152,7 +295,8 @@ func (r *Response) toString(v interface{}) string {
 // Response makes a response instance by context ctx provided.
 func (c *Base) Response(ctx *iris.Context) *Response {
 	return &Response{
-		ctx:  ctx,
-		data: make(iris.Map),
+		ctx:    ctx,
+		data:   make(iris.Map),
+		logger: c.L,
 	}
 }
@@ -0,0 +1,124 @@
+package controllers_test
+
+import (
+	"testing"
+
+	"github.com/sy264115809/golem/controllers"
+	"github.com/sy264115809/golem/models/mgobase"
+
+	"github.com/stretchr/testify/assert"
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func TestResponseWithCursor(t *testing.T) {
+	prev, err := mgobase.EncodeCursor([]interface{}{18, "jack"})
+	assert.NoError(t, err)
+	next, err := mgobase.EncodeCursor([]interface{}{20, "mary"})
+	assert.NoError(t, err)
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/users", func(ctx *iris.Context) {
+		baseController().Response(ctx).WithCursor([]interface{}{18, "jack"}, []interface{}{20, "mary"}).Ok()
+	})
+	app.Get("/empty", func(ctx *iris.Context) {
+		baseController().Response(ctx).WithCursor(nil, nil).Ok()
+	})
+	app.Get("/unencodable", func(ctx *iris.Context) {
+		baseController().Response(ctx).WithCursor(make(chan int), []interface{}{20, "mary"}).Ok()
+	})
+
+	resp := httptest.New(app, t).GET("/users").WithQueryString("status=open&_cursor=old&_page=next").Expect()
+	resp.Status(iris.StatusOK)
+	resp.JSON().Object().
+		ValueEqual("prev_cursor", prev).
+		ValueEqual("next_cursor", next)
+	resp.Header("Link").Equal(`</users?_page=first&status=open>; rel="first", ` +
+		`</users?_cursor=` + prev + `&_page=previous&status=open>; rel="prev", ` +
+		`</users?_cursor=` + next + `&_page=next&status=open>; rel="next", ` +
+		`</users?_page=last&status=open>; rel="last"`)
+
+	// the first page has no previous page
+	resp = httptest.New(app, t).GET("/users").Expect()
+	resp.JSON().Object().NotContainsKey("prev_cursor").ValueEqual("next_cursor", next)
+	resp.Header("Link").Equal(`</users?_page=first>; rel="first", ` +
+		`</users?_cursor=` + next + `&_page=next>; rel="next", ` +
+		`</users?_page=last>; rel="last"`)
+
+	resp = httptest.New(app, t).GET("/users").WithQueryString("_cursor=old&_page=first").Expect()
+	resp.JSON().Object().NotContainsKey("prev_cursor").ValueEqual("next_cursor", next)
+
+	// the last page has no next page
+	resp = httptest.New(app, t).GET("/users").WithQueryString("_page=last").Expect()
+	resp.JSON().Object().ValueEqual("prev_cursor", prev).NotContainsKey("next_cursor")
+	resp.Header("Link").Equal(`</users?_page=first>; rel="first", ` +
+		`</users?_cursor=` + prev + `&_page=previous>; rel="prev", ` +
+		`</users?_page=last>; rel="last"`)
+
+	resp = httptest.New(app, t).GET("/empty").Expect()
+	resp.JSON().Object().NotContainsKey("prev_cursor").NotContainsKey("next_cursor")
+	resp.Header("Link").Equal(`</empty?_page=first>; rel="first", </empty?_page=last>; rel="last"`)
+
+	resp = httptest.New(app, t).GET("/unencodable").WithQueryString("_cursor=old").Expect()
+	resp.Status(iris.StatusOK)
+	resp.JSON().Object().NotContainsKey("prev_cursor").ValueEqual("next_cursor", next)
+}
+
+func TestResponseWithPaginater(t *testing.T) {
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/users", func(ctx *iris.Context) {
+		_, skip, limit := baseController().Pagination(ctx)
+		baseController().Response(ctx).WithPaginater(mgobase.NewPaginater(skip, limit, 95)).Ok()
+	})
+	app.Get("/unlimited", func(ctx *iris.Context) {
+		baseController().Response(ctx).WithPaginater(mgobase.NewPaginater(0, 0, 95)).Ok()
+	})
+	app.Get("/pager", func(ctx *iris.Context) {
+		_, skip, limit := baseController().Pagination(ctx)
+		baseController().Response(ctx).WithPaginater(mgobase.NewPaginater(skip, limit, 95), controllers.PageRange{
+			LeftEdge: 1, LeftCurrent: 1, RightCurrent: 1, RightEdge: 1,
+		}).Ok()
+	})
+
+	resp := httptest.New(app, t).GET("/users").WithQueryString("status=open&_page=2&_limit=10").Expect()
+	resp.Status(iris.StatusOK)
+	resp.JSON().Object().Value("pagination").Object().Equal(map[string]interface{}{
+		"page":        2,
+		"limit":       10,
+		"total_items": 95,
+		"total_pages": 10,
+		"has_prev":    true,
+		"has_next":    true,
+	})
+	resp.Header("X-Total-Count").Equal("95")
+	resp.Header("Link").Equal(`</users?_limit=10&_page=1&status=open>; rel="first", ` +
+		`</users?_limit=10&_page=1&status=open>; rel="prev", ` +
+		`</users?_limit=10&_page=3&status=open>; rel="next", ` +
+		`</users?_limit=10&_page=10&status=open>; rel="last"`)
+
+	resp = httptest.New(app, t).GET("/users").WithQueryString("_page=10&_limit=10").Expect()
+	resp.JSON().Object().Value("pagination").Object().
+		ValueEqual("has_prev", true).
+		ValueEqual("has_next", false)
+	resp.Header("Link").Equal(`</users?_limit=10&_page=1>; rel="first", ` +
+		`</users?_limit=10&_page=9>; rel="prev", ` +
+		`</users?_limit=10&_page=10>; rel="last"`)
+
+	resp = httptest.New(app, t).GET("/pager").WithQueryString("_page=2&_limit=10").Expect()
+	resp.JSON().Object().Value("pagination").Object().Value("items").Array().Equal([]interface{}{
+		map[string]interface{}{"page": 1, "placeholder": false},
+		map[string]interface{}{"page": 2, "placeholder": false},
+		map[string]interface{}{"page": 3, "placeholder": false},
+		map[string]interface{}{"page": 0, "placeholder": true},
+		map[string]interface{}{"page": 10, "placeholder": false},
+	})
+
+	resp = httptest.New(app, t).GET("/unlimited").Expect()
+	resp.JSON().Object().Value("pagination").Object().
+		ValueEqual("limit", 0).
+		ValueEqual("total_pages", 1)
+	resp.Header("Link").Equal(`</unlimited?_page=1>; rel="first", </unlimited?_page=1>; rel="last"`)
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/sy264115809/golem/utils/bsonbuilder"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// FieldType is the type of a field declared in QuerySchema, the query values are parsed as it.
+type FieldType int
+
+// The field types of QuerySchema.
+const (
+	// FieldAny guesses the type of values like QueryBSON does.
+	FieldAny FieldType = iota
+	FieldString
+	FieldInt
+	FieldFloat
+	FieldBool
+	FieldTime
+	FieldObjectID
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case FieldString:
+		return "string"
+	case FieldInt:
+		return "int"
+	case FieldFloat:
+		return "float"
+	case FieldBool:
+		return "bool"
+	case FieldTime:
+		return "time"
+	case FieldObjectID:
+		return "object id"
+	}
+	return "any"
+}
+
+type (
+	// FieldRule declares the type and the permitted operators of a field.
+	FieldRule struct {
+		Type FieldType
+		// Array declares the field is an array of Type, which permits the array operators by default.
+		Array bool
+		// Operators are the permitted operators, DefaultOperators of Type are used if it's empty.
+		Operators []bsonbuilder.Operator
+	}
+
+	// QuerySchema whitelists the fields and operators could be used in QueryBSON, the fields are keyed by
+	// their names in database, e.g. `_id` and `user._id`.
+	QuerySchema struct {
+		Fields map[string]FieldRule
+	}
+)
+
+// DefaultOperators returns the operators permitted for the field type by default,
+// the array fields permit `_size`, `_all` and `_elemMatch` additionally.
+func DefaultOperators(t FieldType) []bsonbuilder.Operator {
+	ops := []bsonbuilder.Operator{
+		bsonbuilder.OperatorEq, bsonbuilder.OperatorNe, bsonbuilder.OperatorExists,
+		bsonbuilder.OperatorIn, bsonbuilder.OperatorNin,
+	}
+
+	switch t {
+	case FieldBool:
+		return ops
+	case FieldString:
+		return append(ops,
+			bsonbuilder.OperatorGt, bsonbuilder.OperatorGte, bsonbuilder.OperatorLt, bsonbuilder.OperatorLte,
+			bsonbuilder.OperatorLike, bsonbuilder.OperatorILike, bsonbuilder.OperatorPrefix,
+		)
+	case FieldInt, FieldFloat:
+		return append(ops,
+			bsonbuilder.OperatorGt, bsonbuilder.OperatorGte, bsonbuilder.OperatorLt, bsonbuilder.OperatorLte,
+			bsonbuilder.OperatorMod,
+		)
+	case FieldAny:
+		return append(ops,
+			bsonbuilder.OperatorGt, bsonbuilder.OperatorGte, bsonbuilder.OperatorLt, bsonbuilder.OperatorLte,
+			bsonbuilder.OperatorLike, bsonbuilder.OperatorILike, bsonbuilder.OperatorPrefix,
+			bsonbuilder.OperatorMod, bsonbuilder.OperatorType,
+		)
+	}
+	return append(ops, bsonbuilder.OperatorGt, bsonbuilder.OperatorGte, bsonbuilder.OperatorLt, bsonbuilder.OperatorLte)
+}
+
+// Allow returns true if the operator is permitted by the rule.
+func (r FieldRule) Allow(op bsonbuilder.Operator) bool {
+	ops := r.Operators
+	if len(ops) == 0 {
+		ops = DefaultOperators(r.Type)
+		if r.Array {
+			ops = append(ops, bsonbuilder.OperatorSize, bsonbuilder.OperatorAll, bsonbuilder.OperatorElemMatch)
+		}
+	}
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// valueType returns the type of values used with the operator, e.g. the values of `_exists` are always boolean
+// and the ones of `_like` are always string.
+func (r FieldRule) valueType(op bsonbuilder.Operator) FieldType {
+	switch op {
+	case bsonbuilder.OperatorExists:
+		return FieldBool
+	case bsonbuilder.OperatorLike, bsonbuilder.OperatorILike, bsonbuilder.OperatorPrefix:
+		return FieldString
+	case bsonbuilder.OperatorSize, bsonbuilder.OperatorMod:
+		return FieldInt
+	case bsonbuilder.OperatorType, bsonbuilder.OperatorElemMatch:
+		return FieldAny
+	}
+	return r.Type
+}
+
+// parseValue parses the query value as the field type, the result is nil for FieldAny.
+func parseValue(t FieldType, val string) (interface{}, error) {
+	switch t {
+	case FieldString:
+		return val, nil
+	case FieldInt:
+		return parseInt(val)
+	case FieldFloat:
+		return parseFloat(val)
+	case FieldBool:
+		return strconv.ParseBool(val)
+	case FieldTime:
+		return parseTime(val, "")
+	case FieldObjectID:
+		// the reason of parseObjectID would repeat the type
+		if !bson.IsObjectIdHex(val) {
+			return nil, errors.New("invalid hex representation")
+		}
+		return bson.ObjectIdHex(val), nil
+	}
+	return nil, nil
+}
+
+// TagFilter is the struct tag used by SchemaOf to declare the permitted operators of a field,
+// e.g. `filter:"eq,ne,in"`, or `filter:"-"` to exclude the field.
+const TagFilter = "filter"
+
+var operatorNames = map[string]bsonbuilder.Operator{
+	"eq":        bsonbuilder.OperatorEq,
+	"ne":        bsonbuilder.OperatorNe,
+	"gt":        bsonbuilder.OperatorGt,
+	"gte":       bsonbuilder.OperatorGte,
+	"lt":        bsonbuilder.OperatorLt,
+	"lte":       bsonbuilder.OperatorLte,
+	"like":      bsonbuilder.OperatorLike,
+	"exists":    bsonbuilder.OperatorExists,
+	"in":        bsonbuilder.OperatorIn,
+	"nin":       bsonbuilder.OperatorNin,
+	"ilike":     bsonbuilder.OperatorILike,
+	"prefix":    bsonbuilder.OperatorPrefix,
+	"size":      bsonbuilder.OperatorSize,
+	"all":       bsonbuilder.OperatorAll,
+	"elemMatch": bsonbuilder.OperatorElemMatch,
+	"type":      bsonbuilder.OperatorType,
+	"mod":       bsonbuilder.OperatorMod,
+}
+
+// SchemaOf derives a QuerySchema from the exported fields of struct model.
+// The field names are taken from the bson tags, or the json tags, or the lowercased field names like mgo does,
+// the nested structs are declared with dotted names and the `inline` ones are flattened.
+// The permitted operators could be declared by the `filter` tag, see TagFilter.
+func SchemaOf(model interface{}) *QuerySchema {
+	schema := &QuerySchema{
+		Fields: make(map[string]FieldRule),
+	}
+
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct {
+		schema.addStruct(t, "", map[reflect.Type]bool{})
+	}
+	return schema
+}
+
+func (s *QuerySchema) addStruct(t reflect.Type, prefix string, visiting map[reflect.Type]bool) {
+	// stop at the recursive types
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		filter := sf.Tag.Get(TagFilter)
+		if filter == "-" {
+			continue
+		}
+
+		name, inline := bsonFieldName(sf)
+		if name == "-" {
+			continue
+		}
+
+		ft, array := sf.Type, false
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			// the object ids and the binary data are stored as single values
+			if ft == typeObjectID || ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Uint8 {
+				break
+			}
+			array = array || ft.Kind() != reflect.Ptr
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && ft != typeTime {
+			if inline {
+				s.addStruct(ft, prefix, visiting)
+				continue
+			}
+			// the array of documents could be filtered by `_elemMatch`
+			if array {
+				s.Fields[prefix+name] = FieldRule{Type: FieldAny, Array: true}
+			}
+			s.addStruct(ft, prefix+name+".", visiting)
+			continue
+		}
+
+		rule := FieldRule{Type: fieldType(ft), Array: array}
+		if filter != "" {
+			for _, op := range strings.Split(filter, ",") {
+				if o, ok := operatorNames[strings.TrimSpace(op)]; ok {
+					rule.Operators = append(rule.Operators, o)
+				}
+			}
+		}
+		s.Fields[prefix+name] = rule
+	}
+}
+
+// bsonFieldName returns the name of the field in database and whether it's inlined.
+func bsonFieldName(sf reflect.StructField) (name string, inline bool) {
+	tag := sf.Tag.Get("bson")
+	if tag == "" && !strings.Contains(string(sf.Tag), `bson:`) {
+		tag = sf.Tag.Get("json")
+	}
+
+	parts := strings.Split(tag, ",")
+	for _, flag := range parts[1:] {
+		if flag == "inline" {
+			inline = true
+		}
+	}
+
+	name = parts[0]
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	return
+}
+
+func fieldType(t reflect.Type) FieldType {
+	switch t {
+	case typeTime:
+		return FieldTime
+	case typeObjectID:
+		return FieldObjectID
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return FieldString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return FieldInt
+	case reflect.Float32, reflect.Float64:
+		return FieldFloat
+	case reflect.Bool:
+		return FieldBool
+	}
+	return FieldAny
+}
+
+// check returns the value parsed by the rule of key, or the ParamError if the key or operator isn't permitted,
+// or the value is malformed. The value is nil if the field is FieldAny.
+func (s *QuerySchema) check(param, key string, op bsonbuilder.Operator, val string) (interface{}, *ParamError) {
+	rule, ok := s.Fields[key]
+	if !ok {
+		return nil, &ParamError{Key: param, Value: val, Reason: fmt.Sprintf("field %s is not allowed", key)}
+	}
+	if !rule.Allow(op) {
+		return nil, &ParamError{Key: param, Value: val, Reason: fmt.Sprintf("operator is not allowed on field %s", key)}
+	}
+
+	t := rule.valueType(op)
+	res, err := parseValue(t, val)
+	if err != nil {
+		return nil, &ParamError{Key: param, Value: val, Reason: fmt.Sprintf("invalid %s, %s", t, reason(err))}
+	}
+	return res, nil
+}
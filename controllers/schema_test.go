@@ -0,0 +1,119 @@
+package controllers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sy264115809/golem/controllers"
+	"github.com/sy264115809/golem/utils/bsonbuilder"
+
+	"github.com/stretchr/testify/assert"
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type schemaBase struct {
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+type schemaModel struct {
+	schemaBase `bson:",inline"`
+	ID         bson.ObjectId `bson:"_id"`
+	Name       string        `bson:"name" filter:"eq,like"`
+	Age        int           `json:"age"`
+	Score      *float64
+	Active     bool     `bson:"active"`
+	Tags       []string `bson:"tags"`
+	Items      []struct {
+		Qty int    `bson:"qty"`
+		SKU string `bson:"sku" filter:"eq,in"`
+	} `bson:"items"`
+	Password string `bson:"password" filter:"-"`
+	Ignored  string `bson:"-"`
+	Owner    struct {
+		ID   bson.ObjectId `bson:"_id"`
+		Meta interface{}   `bson:"meta"`
+	} `bson:"owner"`
+	secret string
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema := controllers.SchemaOf(&schemaModel{})
+
+	expected := map[string]controllers.FieldRule{
+		"created_at": {Type: controllers.FieldTime},
+		"_id":        {Type: controllers.FieldObjectID},
+		"name":       {Type: controllers.FieldString, Operators: []bsonbuilder.Operator{bsonbuilder.OperatorEq, bsonbuilder.OperatorLike}},
+		"age":        {Type: controllers.FieldInt},
+		"score":      {Type: controllers.FieldFloat},
+		"active":     {Type: controllers.FieldBool},
+		"tags":       {Type: controllers.FieldString, Array: true},
+		"items":      {Type: controllers.FieldAny, Array: true},
+		"items.qty":  {Type: controllers.FieldInt},
+		"items.sku":  {Type: controllers.FieldString, Operators: []bsonbuilder.Operator{bsonbuilder.OperatorEq, bsonbuilder.OperatorIn}},
+		"owner._id":  {Type: controllers.FieldObjectID},
+		"owner.meta": {Type: controllers.FieldAny},
+	}
+	assert.Equal(t, expected, schema.Fields)
+
+	assert.True(t, schema.Fields["name"].Allow(bsonbuilder.OperatorLike))
+	assert.False(t, schema.Fields["name"].Allow(bsonbuilder.OperatorGt))
+	assert.True(t, schema.Fields["age"].Allow(bsonbuilder.OperatorGt))
+	assert.False(t, schema.Fields["age"].Allow(bsonbuilder.OperatorLike))
+	assert.False(t, schema.Fields["active"].Allow(bsonbuilder.OperatorLt))
+	assert.True(t, schema.Fields["tags"].Allow(bsonbuilder.OperatorAll))
+	assert.False(t, schema.Fields["age"].Allow(bsonbuilder.OperatorSize))
+}
+
+func TestQueryBSONWithSchema(t *testing.T) {
+	schema := controllers.SchemaOf(schemaModel{})
+
+	var (
+		actual bson.M
+		err    error
+	)
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Get("/query-bson", func(ctx *iris.Context) {
+		actual, err = baseController().QueryBSONWithOptions(ctx, controllers.QueryOptions{Schema: schema})
+	})
+
+	t.Run("permitted", func(t *testing.T) {
+		httptest.New(app, t).GET("/query-bson").
+			WithQueryString("name=123&age_gte=18&age_in=20,30&active=true&tags_all=go,db&owner.id=58db2700cf2f6715b00021a7" +
+				"&items_elemMatch.qty_gt=5&items_elemMatch.sku_in=a,b&_page=2").
+			Expect()
+
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{
+			"name":      bson.M{"$eq": "123"},
+			"age":       bson.M{"$gte": 18, "$in": []interface{}{20, 30}},
+			"active":    bson.M{"$eq": true},
+			"tags":      bson.M{"$all": []interface{}{"go", "db"}},
+			"owner._id": bson.M{"$eq": bson.ObjectIdHex("58db2700cf2f6715b00021a7")},
+			"items":     bson.M{"$elemMatch": bson.M{"qty": bson.M{"$gt": 5}, "sku": bson.M{"$in": []interface{}{"a", "b"}}}},
+		}, actual)
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		httptest.New(app, t).GET("/query-bson").
+			WithQueryString("name_gt=a&age=ten&age_in=1,x&password=secret&id=42&active_exists=true" +
+				"&age_size=2&items_elemMatch.sku_like=a").
+			Expect()
+
+		assert.Equal(t, bson.M{"active": bson.M{"$exists": true}}, actual)
+		assert.IsType(t, &controllers.ParamErrors{}, err)
+		assert.Equal(t, []controllers.ParamError{
+			{Key: "age", Value: "ten", Reason: "invalid int, invalid syntax"},
+			{Key: "age_in", Value: "1,x", Reason: "invalid int, invalid syntax"},
+			{Key: "age_size", Value: "2", Reason: "operator is not allowed on field age"},
+			{Key: "id", Value: "42", Reason: "invalid object id, invalid hex representation"},
+			{Key: "items_elemMatch.sku_like", Value: "a", Reason: "operator is not allowed on field items.sku"},
+			{Key: "name_gt", Value: "a", Reason: "operator is not allowed on field name"},
+			{Key: "password", Value: "secret", Reason: "field password is not allowed"},
+		}, err.(*controllers.ParamErrors).Errors)
+	})
+}
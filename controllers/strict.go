@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type (
+	// ParamError describes a malformed query or form parameter.
+	ParamError struct {
+		Key    string `json:"key"`
+		Value  string `json:"value"`
+		Reason string `json:"reason"`
+	}
+
+	// ParamErrors lists every malformed parameter collected by StrictParser.
+	ParamErrors struct {
+		Errors []ParamError `json:"errors"`
+	}
+
+	// StrictParser parses the query and form parameters like the Query* and Param* helpers of Base do,
+	// but records every malformed value instead of falling back to the default silently.
+	// The default value is still returned for the absent or malformed parameters, so the handler can
+	// parse all the parameters first and check `Err` once.
+	StrictParser struct {
+		ctx  *iris.Context
+		errs []ParamError
+	}
+)
+
+func (e *ParamErrors) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, pe := range e.Errors {
+		messages[i] = fmt.Sprintf("%s: invalid value %q, %s", pe.Key, pe.Value, pe.Reason)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Strict returns a StrictParser of the request.
+func (c *Base) Strict(ctx *iris.Context) *StrictParser {
+	return &StrictParser{ctx: ctx}
+}
+
+// Err returns *ParamErrors lists every malformed parameter parsed so far, or nil if there is none.
+func (p *StrictParser) Err() error {
+	if len(p.errs) == 0 {
+		return nil
+	}
+	return &ParamErrors{Errors: p.errs}
+}
+
+// query returns the non-empty values of the url query, the empty values are treated as absent.
+func (p *StrictParser) query(key string) []string {
+	return nonEmpty(p.ctx.URLParamsAsMulti()[key])
+}
+
+// form returns the non-empty values of the query and body (if POST, PUT or PATCH).
+func (p *StrictParser) form(key string) []string {
+	return nonEmpty(p.ctx.FormValues()[key])
+}
+
+func nonEmpty(strs []string) (res []string) {
+	for _, str := range strs {
+		if str != "" {
+			res = append(res, str)
+		}
+	}
+	return
+}
+
+func first(strs []string) []string {
+	if len(strs) > 1 {
+		return strs[:1]
+	}
+	return strs
+}
+
+// parseAll parses every string and records the malformed ones.
+func parseAll[T any](p *StrictParser, key string, strs []string, parse func(str string) (T, error)) (vals []T) {
+	for _, str := range strs {
+		val, err := parse(str)
+		if err != nil {
+			p.errs = append(p.errs, ParamError{Key: key, Value: str, Reason: reason(err)})
+			continue
+		}
+		vals = append(vals, val)
+	}
+	return
+}
+
+// reason strips the function name and the input of strconv errors, which are reported by ParamError already.
+func reason(err error) string {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		return numErr.Err.Error()
+	}
+	return err.Error()
+}
+
+func parseInt(str string) (int, error) {
+	i, err := strconv.ParseInt(str, 10, 0)
+	return int(i), err
+}
+
+func parseFloat(str string) (float64, error) {
+	return strconv.ParseFloat(str, 64)
+}
+
+func parseObjectID(str string) (bson.ObjectId, error) {
+	if !bson.IsObjectIdHex(str) {
+		return "", fmt.Errorf("invalid object id")
+	}
+	return bson.ObjectIdHex(str), nil
+}
+
+// QueryInt parses query parameter as int if key exist, or returns defaultVal.
+func (p *StrictParser) QueryInt(key string, defaultVal int) int {
+	if ints := parseAll(p, key, first(p.query(key)), parseInt); len(ints) > 0 {
+		return ints[0]
+	}
+	return defaultVal
+}
+
+// QueryInts parses query parameter as int array if key exist, or returns defaultVal.
+func (p *StrictParser) QueryInts(key string, defaultVal []int) []int {
+	if ints := parseAll(p, key, p.query(key), parseInt); ints != nil {
+		return ints
+	}
+	return defaultVal
+}
+
+// QueryFloat parses query parameter as float64 if exist, or returns defaultVal.
+func (p *StrictParser) QueryFloat(key string, defaultVal float64) float64 {
+	if floats := parseAll(p, key, first(p.query(key)), parseFloat); len(floats) > 0 {
+		return floats[0]
+	}
+	return defaultVal
+}
+
+// QueryFloats parses query parameter as float64 array if exist, or returns defaultVal.
+func (p *StrictParser) QueryFloats(key string, defaultVal []float64) []float64 {
+	if floats := parseAll(p, key, p.query(key), parseFloat); floats != nil {
+		return floats
+	}
+	return defaultVal
+}
+
+// QueryBool parses query parameter as boolean if exist, or returns defaultVal.
+func (p *StrictParser) QueryBool(key string, defaultVal bool) bool {
+	if bools := parseAll(p, key, first(p.query(key)), strconv.ParseBool); len(bools) > 0 {
+		return bools[0]
+	}
+	return defaultVal
+}
+
+// QueryBools parses query parameter as boolean array if exist, or returns defaultVal.
+func (p *StrictParser) QueryBools(key string, defaultVal []bool) []bool {
+	if bools := parseAll(p, key, p.query(key), strconv.ParseBool); bools != nil {
+		return bools
+	}
+	return defaultVal
+}
+
+// QueryTime parses query parameter as time.Time with QueryFormatDateTime if exist, or returns defaultVal.
+func (p *StrictParser) QueryTime(key string, defaultVal time.Time) time.Time {
+	parse := func(str string) (time.Time, error) {
+		return time.Parse(QueryFormatDateTime, str)
+	}
+	if dates := parseAll(p, key, first(p.query(key)), parse); len(dates) > 0 {
+		return dates[0]
+	}
+	return defaultVal
+}
+
+// QueryObjectID parses query parameter as bson.ObjectId if exist, or returns defaultVal.
+func (p *StrictParser) QueryObjectID(key string, defaultVal bson.ObjectId) bson.ObjectId {
+	if ids := parseAll(p, key, first(p.query(key)), parseObjectID); len(ids) > 0 {
+		return ids[0]
+	}
+	return defaultVal
+}
+
+// ParamInt takes first value for the named component of the query and parses it to int.
+// POST, PUT and PATCH body parameters take precedence over URL query string values.
+// returns the default value if key is not present.
+func (p *StrictParser) ParamInt(key string, defaultVal int) int {
+	if ints := parseAll(p, key, first(p.form(key)), parseInt); len(ints) > 0 {
+		return ints[0]
+	}
+	return defaultVal
+}
+
+// ParamInts takes values for the named component of the query and body (if POST, PUT or PATCH).
+// returns the default value if key is not present.
+func (p *StrictParser) ParamInts(key string, defaultVal []int) []int {
+	if ints := parseAll(p, key, p.form(key), parseInt); ints != nil {
+		return ints
+	}
+	return defaultVal
+}
+
+// ParamFloat takes first value for the named component of the query and parses it to float64.
+// POST, PUT and PATCH body parameters take precedence over URL query string values.
+// returns the default value if key is not present.
+func (p *StrictParser) ParamFloat(key string, defaultVal float64) float64 {
+	if floats := parseAll(p, key, first(p.form(key)), parseFloat); len(floats) > 0 {
+		return floats[0]
+	}
+	return defaultVal
+}
+
+// ParamFloats takes values for the named component of the query and body (if POST, PUT or PATCH).
+// returns the default value if key is not present.
+func (p *StrictParser) ParamFloats(key string, defaultVal []float64) []float64 {
+	if floats := parseAll(p, key, p.form(key), parseFloat); floats != nil {
+		return floats
+	}
+	return defaultVal
+}
+
+// ParamBool takes first value for the named component of the query and parses it to boolean.
+// POST, PUT and PATCH body parameters take precedence over URL query string values.
+// returns the default value if key is not present.
+func (p *StrictParser) ParamBool(key string, defaultVal bool) bool {
+	if bools := parseAll(p, key, first(p.form(key)), strconv.ParseBool); len(bools) > 0 {
+		return bools[0]
+	}
+	return defaultVal
+}
+
+// ParamBools takes values for the named component of the query and body (if POST, PUT or PATCH).
+// returns the default value if key is not present.
+func (p *StrictParser) ParamBools(key string, defaultVal []bool) []bool {
+	if bools := parseAll(p, key, p.form(key), strconv.ParseBool); bools != nil {
+		return bools
+	}
+	return defaultVal
+}
@@ -0,0 +1,67 @@
+package controllers_test
+
+import (
+	"testing"
+	"time"
+
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func TestStrictParser(t *testing.T) {
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Post("/strict", func(ctx *iris.Context) {
+		p := baseController().Strict(ctx)
+		page := p.QueryInt("page", 1)
+		ids := p.QueryInts("id", nil)
+		ratio := p.QueryFloat("ratio", 0.5)
+		verbose := p.ParamBool("verbose", false)
+		since := p.QueryTime("since", time.Time{})
+		owner := p.QueryObjectID("owner", "")
+		if err := p.Err(); err != nil {
+			baseController().RespondError(ctx, err)
+			return
+		}
+		ctx.JSON(iris.StatusOK, map[string]interface{}{
+			"page":    page,
+			"ids":     ids,
+			"ratio":   ratio,
+			"verbose": verbose,
+			"since":   since,
+			"owner":   owner,
+		})
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		httptest.New(app, t).POST("/strict").
+			WithQueryString("page=2&id=1&id=2&since=2018-01-02T03:04:05&owner=5a1b2c3d4e5f6a7b8c9d0e1f&ratio=").
+			WithFormField("verbose", "true").
+			Expect().Status(iris.StatusOK).JSON().Object().Equal(map[string]interface{}{
+			"page":    2,
+			"ids":     []int{1, 2},
+			"ratio":   0.5,
+			"verbose": true,
+			"since":   "2018-01-02T03:04:05Z",
+			"owner":   "5a1b2c3d4e5f6a7b8c9d0e1f",
+		})
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		obj := httptest.New(app, t).POST("/strict").
+			WithQueryString("page=x&id=1&id=two&ratio=1e500&since=yesterday&owner=42").
+			WithFormField("verbose", "maybe").
+			Expect().Status(iris.StatusBadRequest).JSON().Object()
+
+		obj.Value("code").Equal(40004)
+		obj.Value("errors").Array().Equal([]map[string]interface{}{
+			{"key": "page", "value": "x", "reason": "invalid syntax"},
+			{"key": "id", "value": "two", "reason": "invalid syntax"},
+			{"key": "ratio", "value": "1e500", "reason": "value out of range"},
+			{"key": "verbose", "value": "maybe", "reason": "invalid syntax"},
+			{"key": "since", "value": "yesterday", "reason": `parsing time "yesterday" as "2006-01-02T15:04:05": cannot parse "yesterday" as "2006"`},
+			{"key": "owner", "value": "42", "reason": "invalid object id"},
+		})
+	})
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	ut "github.com/go-playground/universal-translator"
+	validator "gopkg.in/go-playground/validator.v9"
+	iris "gopkg.in/kataras/iris.v6"
+)
+
+type (
+	// ValidationError lists every field failed in validation.
+	ValidationError struct {
+		Errors []FieldError `json:"errors"`
+
+		errs validator.ValidationErrors
+	}
+
+	// FieldError describes a field failed in validation.
+	FieldError struct {
+		// Field is the json name of the field, nested fields are joined by dot, e.g. `address.city`.
+		Field   string `json:"field"`
+		Tag     string `json:"tag"`
+		Param   string `json:"param,omitempty"`
+		Message string `json:"message"`
+	}
+)
+
+// ValidationFailedStatus is the http status used to render ValidationError.
+var ValidationFailedStatus = iris.StatusUnprocessableEntity
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the validator.ValidationErrors converted, so that it's still reachable by errors.As.
+func (e *ValidationError) Unwrap() error {
+	if e.errs == nil {
+		return nil
+	}
+	return e.errs
+}
+
+var (
+	validationMessagesLock sync.RWMutex
+	// the formats are applied with field name, param and tag in order.
+	validationMessages = map[string]string{
+		"required": "%[1]s is required",
+		"len":      "%[1]s must be %[2]s in length",
+		"min":      "%[1]s must be at least %[2]s",
+		"max":      "%[1]s must be at most %[2]s",
+		"eq":       "%[1]s must be equal to %[2]s",
+		"ne":       "%[1]s must not be equal to %[2]s",
+		"gt":       "%[1]s must be greater than %[2]s",
+		"gte":      "%[1]s must be greater than or equal to %[2]s",
+		"lt":       "%[1]s must be less than %[2]s",
+		"lte":      "%[1]s must be less than or equal to %[2]s",
+		"oneof":    "%[1]s must be one of [%[2]s]",
+		"email":    "%[1]s must be a valid email address",
+		"url":      "%[1]s must be a valid url",
+	}
+)
+
+// RegisterValidationMessage sets the message format of a validation tag,
+// the format is applied with the field name, the tag param and the tag itself in order, e.g. `%[1]s must be at least %[2]s`.
+func RegisterValidationMessage(tag, format string) {
+	validationMessagesLock.Lock()
+	defer validationMessagesLock.Unlock()
+	validationMessages[tag] = format
+}
+
+func validationMessage(field, tag, param string) string {
+	validationMessagesLock.RLock()
+	format, ok := validationMessages[tag]
+	validationMessagesLock.RUnlock()
+
+	if !ok {
+		format = "%[1]s failed on the '%[3]s' tag"
+	}
+	return fmt.Sprintf(format, field, param, tag)
+}
+
+// newValidationError converts validator.ValidationErrors to ValidationError, the message is translated if `trans` is not nil.
+func newValidationError(errs validator.ValidationErrors, trans ut.Translator) *ValidationError {
+	ve := &ValidationError{
+		Errors: make([]FieldError, len(errs)),
+		errs:   errs,
+	}
+
+	for i, fe := range errs {
+		field := fe.Namespace()
+		// strip the name of the top level struct
+		if idx := strings.Index(field, "."); idx >= 0 {
+			field = field[idx+1:]
+		}
+
+		message := ""
+		if trans != nil {
+			message = fe.Translate(trans)
+		} else {
+			message = validationMessage(field, fe.Tag(), fe.Param())
+		}
+
+		ve.Errors[i] = FieldError{
+			Field:   field,
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: message,
+		}
+	}
+	return ve
+}
+
+// jsonTagName returns the json name of a struct field, so the failing fields are reported in the names clients know.
+func jsonTagName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// ValidationFailed renders response with ValidationFailedStatus and an `errors` array lists every failing field.
+func (r *Response) ValidationFailed(err *ValidationError) {
+	r.data["errors"] = err.Errors
+	if r.code == nil {
+		r.code = CodeValidationFailed
+	}
+	r.render(ValidationFailedStatus, err.Error())
+}
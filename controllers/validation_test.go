@@ -0,0 +1,70 @@
+package controllers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sy264115809/golem/controllers"
+
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/stretchr/testify/assert"
+	validator "gopkg.in/go-playground/validator.v9"
+	zh_translations "gopkg.in/go-playground/validator.v9/translations/zh"
+)
+
+func TestValidationError(t *testing.T) {
+	type address struct {
+		City string `json:"city" validate:"required"`
+	}
+
+	type user struct {
+		Name    string  `json:"name" validate:"required"`
+		Age     int     `json:"age" validate:"gte=18"`
+		Role    string  `json:"role" validate:"oneof=admin member"`
+		Address address `json:"address"`
+		Nick    string  `validate:"max=3"`
+	}
+
+	err := baseController().Validate(user{Role: "guest", Nick: "nickname"})
+	assert.IsType(t, &controllers.ValidationError{}, err)
+
+	expected := []controllers.FieldError{
+		{Field: "name", Tag: "required", Message: "name is required"},
+		{Field: "age", Tag: "gte", Param: "18", Message: "age must be greater than or equal to 18"},
+		{Field: "role", Tag: "oneof", Param: "admin member", Message: "role must be one of [admin member]"},
+		{Field: "address.city", Tag: "required", Message: "address.city is required"},
+		{Field: "Nick", Tag: "max", Param: "3", Message: "Nick must be at most 3"},
+	}
+	assert.Equal(t, expected, err.(*controllers.ValidationError).Errors)
+
+	var errs validator.ValidationErrors
+	if assert.True(t, errors.As(err, &errs)) {
+		assert.Len(t, errs, len(expected))
+	}
+
+	controllers.RegisterValidationMessage("required", "%[1]s can't be blank")
+	defer controllers.RegisterValidationMessage("required", "%[1]s is required")
+
+	err = baseController().Validate(address{})
+	assert.EqualError(t, err, "city can't be blank")
+}
+
+func TestValidationErrorWithTranslator(t *testing.T) {
+	type user struct {
+		Name string `json:"name" validate:"required"`
+		Age  int    `json:"age" validate:"gte=18"`
+	}
+
+	trans, _ := ut.New(zh.New()).GetTranslator("zh")
+	assert.NoError(t, zh_translations.RegisterDefaultTranslations(controllers.DefaultValidator(), trans))
+
+	err := baseController().SetTranslator(trans).Validate(user{Age: 17})
+	assert.IsType(t, &controllers.ValidationError{}, err)
+
+	expected := []controllers.FieldError{
+		{Field: "name", Tag: "required", Message: "name为必填字段"},
+		{Field: "age", Tag: "gte", Param: "18", Message: "age必须大于或等于18"},
+	}
+	assert.Equal(t, expected, err.(*controllers.ValidationError).Errors)
+}
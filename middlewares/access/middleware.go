@@ -3,6 +3,8 @@ package access
 import (
 	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"time"
 
 	iris "gopkg.in/kataras/iris.v6"
@@ -21,24 +23,55 @@ var (
 	reset   = string([]byte{27, 91, 48, 109})
 )
 
+const (
+	// RequestIDKey is the key of request id stored in the iris context.
+	RequestIDKey = "request_id"
+	// ResponseHeaderRequestID is the response header which carries the request id.
+	ResponseHeaderRequestID = "X-REQID"
+
+	maxRequestIDLength = 128
+)
+
+// RequestHeadersRequestID are the request headers where an upstream request id will be honored, in order.
+var RequestHeadersRequestID = []string{"X-Request-ID", "X-REQID"}
+
 // New instantiates a Logger middleware with the specified writter buffer.
 // Example: os.Stdout, a file opened in write mode, a socket...
 func New(out io.Writer, prefix string, notlogged ...string) iris.HandlerFunc {
+	return NewWithConfig(Config{
+		Out:       out,
+		Prefix:    prefix,
+		NotLogged: notlogged,
+	})
+}
+
+// NewWithConfig instantiates a Logger middleware with the specified config.
+func NewWithConfig(c Config) iris.HandlerFunc {
+	if c.Out == nil {
+		c.Out = os.Stdout
+	}
+
 	var skip map[string]struct{}
 
-	if length := len(notlogged); length > 0 {
+	if length := len(c.NotLogged); length > 0 {
 		skip = make(map[string]struct{}, length)
 
-		for _, path := range notlogged {
+		for _, path := range c.NotLogged {
 			skip[path] = struct{}{}
 		}
 	}
 
+	colorful := isTerminal(c.Out)
+
 	return func(ctx *iris.Context) {
 		// Start timer
 		start := time.Now()
 		path := ctx.Request.RequestURI
-		reqID := uuid.NewV1().String()
+		reqID := requestID(ctx)
+
+		// expose request id to the handlers and the client before the body is written
+		ctx.Set(RequestIDKey, reqID)
+		ctx.SetHeader(ResponseHeaderRequestID, reqID)
 
 		// Process request
 		ctx.Next()
@@ -47,29 +80,118 @@ func New(out io.Writer, prefix string, notlogged ...string) iris.HandlerFunc {
 		if _, ok := skip[path]; !ok {
 			// Stop timer
 			end := time.Now()
-			latency := end.Sub(start)
-
-			remoteAddr := ctx.RemoteAddr()
-			method := ctx.Method()
-			statusCode := ctx.StatusCode()
-			statusColor := colorForStatus(statusCode)
-			methodColor := colorForMethod(method)
-
-			fmt.Fprintf(out, "[%s] %v | %s |%s %3d %s| %13v | %s |%s  %s %-7s %s\n",
-				prefix,
-				end.Format("2006/01/02 - 15:04:05"),
-				reqID,
-				statusColor, statusCode, reset,
-				latency,
-				remoteAddr,
-				methodColor, reset, method,
-				path,
-			)
+
+			r := Record{
+				Time:         end,
+				Prefix:       c.Prefix,
+				RequestID:    reqID,
+				Method:       ctx.Method(),
+				Path:         path,
+				Status:       ctx.StatusCode(),
+				Latency:      end.Sub(start),
+				BytesWritten: bytesWritten(ctx),
+				RemoteAddr:   ctx.RemoteAddr(),
+				UserAgent:    ctx.RequestHeader("User-Agent"),
+			}
+
+			if c.Route != nil {
+				r.Route = c.Route(ctx)
+			}
+
+			if len(c.Headers) > 0 {
+				r.Headers = make(map[string]string, len(c.Headers))
+				for _, key := range c.Headers {
+					if val := ctx.RequestHeader(key); val != "" {
+						r.Headers[key] = val
+					}
+				}
+			}
+
+			switch c.Format {
+			case FormatJSON:
+				writeJSON(c.Out, r)
+			case FormatLogfmt:
+				writeLogfmt(c.Out, r)
+			default:
+				writeText(c.Out, r, colorful)
+			}
 		}
+	}
+}
+
+// requestID returns the valid request id from the request headers, or generates a new one.
+func requestID(ctx *iris.Context) string {
+	for _, header := range RequestHeadersRequestID {
+		if id := ctx.RequestHeader(header); isValidRequestID(id) {
+			return id
+		}
+	}
+	return uuid.NewV1().String()
+}
+
+// isValidRequestID prevents the client from injecting arbitrary content into logs through the request id.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID returns the request id set by the access middleware, or an empty string if not exist.
+func RequestID(ctx *iris.Context) string {
+	return ctx.GetString(RequestIDKey)
+}
+
+func writeText(out io.Writer, r Record, colorful bool) {
+	statusColor, methodColor, resetColor := "", "", ""
+	if colorful {
+		statusColor = colorForStatus(r.Status)
+		methodColor = colorForMethod(r.Method)
+		resetColor = reset
+	}
+
+	fmt.Fprintf(out, "[%s] %v | %s |%s %3d %s| %13v | %s |%s  %s %-7s %s\n",
+		r.Prefix,
+		r.Time.Format("2006/01/02 - 15:04:05"),
+		r.RequestID,
+		statusColor, r.Status, resetColor,
+		r.Latency,
+		r.RemoteAddr,
+		methodColor, resetColor, r.Method,
+		r.Path,
+	)
+}
+
+// bytesWritten returns the length of response body if the response writer reports it,
+// or falls back to the Content-Length header.
+func bytesWritten(ctx *iris.Context) int {
+	if w, ok := ctx.ResponseWriter.(interface {
+		Written() int
+	}); ok {
+		return w.Written()
+	}
+
+	n, _ := strconv.Atoi(ctx.ResponseWriter.Header().Get("Content-Length"))
+	return n
+}
+
+// isTerminal returns true if the out is a character device, e.g. os.Stdout of an interactive shell.
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
 
-		// set X-REQID header
-		ctx.SetHeader("X-REQID", reqID)
+	stat, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return stat.Mode()&os.ModeCharDevice != 0
 }
 
 func colorForStatus(code int) string {
@@ -0,0 +1,123 @@
+package access
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func newApp(c Config) *iris.Framework {
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Use(NewWithConfig(c))
+	app.Get("/users/:id", func(ctx *iris.Context) {
+		ctx.WriteString(ctx.Param("id"))
+	})
+	app.Get("/ping", func(ctx *iris.Context) {
+		ctx.WriteString("pong")
+	})
+	return app
+}
+
+func TestNewWithConfig(t *testing.T) {
+	var out bytes.Buffer
+
+	app := newApp(Config{
+		Out:       &out,
+		Format:    FormatJSON,
+		NotLogged: []string{"/ping"},
+		Headers:   []string{"X-Tenant", "X-Absent"},
+	})
+
+	e := httptest.New(app, t)
+	e.GET("/users/42").WithHeader("X-Tenant", "acme").WithHeader("User-Agent", "test").
+		Expect().Status(iris.StatusOK).Body().Equal("42")
+	e.GET("/ping").Expect().Status(iris.StatusOK)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 1) {
+		var r map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &r))
+		assert.Equal(t, "GET", r["method"])
+		assert.Equal(t, "/users/42", r["path"])
+		assert.Equal(t, float64(iris.StatusOK), r["status"])
+		assert.Equal(t, float64(2), r["bytes_written"])
+		assert.Equal(t, "test", r["user_agent"])
+		assert.Equal(t, map[string]interface{}{"X-Tenant": "acme"}, r["headers"])
+		assert.NotEmpty(t, r["request_id"])
+		assert.NotEmpty(t, r["remote_addr"])
+	}
+}
+
+func TestNewWithConfigLogfmt(t *testing.T) {
+	var out bytes.Buffer
+
+	app := newApp(Config{
+		Out:    &out,
+		Format: FormatLogfmt,
+		Route: func(ctx *iris.Context) string {
+			return "/users/:id"
+		},
+	})
+
+	e := httptest.New(app, t)
+	e.GET("/users/42").Expect().Status(iris.StatusOK)
+
+	assert.Contains(t, out.String(), " method=GET path=/users/42 route=/users/:id status=200 ")
+	assert.True(t, strings.HasSuffix(out.String(), "\n"))
+}
+
+func TestRequestID(t *testing.T) {
+	var seen []string
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Use(NewWithConfig(Config{Out: ioutil.Discard}))
+	app.Get("/", func(ctx *iris.Context) {
+		// the response header is set before the handlers write the body
+		assert.Equal(t, RequestID(ctx), ctx.ResponseWriter.Header().Get(ResponseHeaderRequestID))
+		seen = append(seen, RequestID(ctx))
+		ctx.WriteString("ok")
+	})
+
+	e := httptest.New(app, t)
+	e.GET("/").WithHeader("X-Request-ID", "upstream-1").
+		Expect().Status(iris.StatusOK).Header(ResponseHeaderRequestID).Equal("upstream-1")
+	e.GET("/").WithHeader("X-REQID", "upstream-2").
+		Expect().Status(iris.StatusOK).Header(ResponseHeaderRequestID).Equal("upstream-2")
+	e.GET("/").WithHeader("X-Request-ID", "upstream-3").WithHeader("X-REQID", "upstream-4").
+		Expect().Status(iris.StatusOK).Header(ResponseHeaderRequestID).Equal("upstream-3")
+
+	// invalid request ids are replaced by generated ones
+	e.GET("/").WithHeader("X-Request-ID", "bad id\ninjected=1").
+		Expect().Status(iris.StatusOK).Header(ResponseHeaderRequestID).NotEqual("bad id\ninjected=1")
+	e.GET("/").WithHeader("X-Request-ID", strings.Repeat("a", maxRequestIDLength+1)).
+		Expect().Status(iris.StatusOK).Header(ResponseHeaderRequestID).Length().Equal(36)
+	e.GET("/").Expect().Status(iris.StatusOK).Header(ResponseHeaderRequestID).Length().Equal(36)
+
+	if assert.Len(t, seen, 6) {
+		assert.Equal(t, []string{"upstream-1", "upstream-2", "upstream-3"}, seen[:3])
+		assert.NotEqual(t, seen[4], seen[5])
+	}
+}
+
+func TestIsValidRequestID(t *testing.T) {
+	assert.True(t, isValidRequestID("2b1f7a3c-1e2d-11e7-93ae-92361f002671"))
+	assert.True(t, isValidRequestID("a"))
+	assert.True(t, isValidRequestID(strings.Repeat("a", maxRequestIDLength)))
+
+	assert.False(t, isValidRequestID(""))
+	assert.False(t, isValidRequestID(strings.Repeat("a", maxRequestIDLength+1)))
+	assert.False(t, isValidRequestID("with space"))
+	assert.False(t, isValidRequestID("line\nbreak"))
+	assert.False(t, isValidRequestID("tab\t"))
+	assert.False(t, isValidRequestID("del\x7f"))
+	assert.False(t, isValidRequestID("ünicode"))
+}
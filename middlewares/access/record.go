@@ -0,0 +1,148 @@
+package access
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	iris "gopkg.in/kataras/iris.v6"
+)
+
+// Format is the output format of access log.
+type Format int
+
+const (
+	// FormatText outputs a human-readable line, which is colored if the writer is a terminal.
+	FormatText Format = iota
+	// FormatJSON outputs a json object per line.
+	FormatJSON
+	// FormatLogfmt outputs logfmt style key=value pairs per line.
+	FormatLogfmt
+)
+
+type (
+	// Config is the configuration of access log middleware.
+	Config struct {
+		// Out is where the logs are written to, os.Stdout by default.
+		Out io.Writer
+		// Prefix is prepended to the text format log.
+		Prefix string
+		// Format is the output format, FormatText by default.
+		Format Format
+		// NotLogged are the request uris that will not be logged.
+		NotLogged []string
+		// Headers are the request headers that will be logged if present.
+		Headers []string
+		// Route returns the matched route of the request, it's omitted if nil.
+		Route func(*iris.Context) string
+	}
+
+	// Record is an entry of access log.
+	Record struct {
+		Time         time.Time
+		Prefix       string
+		RequestID    string
+		Method       string
+		Path         string
+		Route        string
+		Status       int
+		Latency      time.Duration
+		BytesWritten int
+		RemoteAddr   string
+		UserAgent    string
+		Headers      map[string]string
+	}
+)
+
+// MarshalJSON encodes the record with snake case keys, the latency is in seconds.
+func (r Record) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Time         string            `json:"time"`
+		Prefix       string            `json:"prefix,omitempty"`
+		RequestID    string            `json:"request_id"`
+		Method       string            `json:"method"`
+		Path         string            `json:"path"`
+		Route        string            `json:"route,omitempty"`
+		Status       int               `json:"status"`
+		Latency      float64           `json:"latency"`
+		BytesWritten int               `json:"bytes_written"`
+		RemoteAddr   string            `json:"remote_addr"`
+		UserAgent    string            `json:"user_agent"`
+		Headers      map[string]string `json:"headers,omitempty"`
+	}{
+		Time:         r.Time.Format(time.RFC3339Nano),
+		Prefix:       r.Prefix,
+		RequestID:    r.RequestID,
+		Method:       r.Method,
+		Path:         r.Path,
+		Route:        r.Route,
+		Status:       r.Status,
+		Latency:      r.Latency.Seconds(),
+		BytesWritten: r.BytesWritten,
+		RemoteAddr:   r.RemoteAddr,
+		UserAgent:    r.UserAgent,
+		Headers:      r.Headers,
+	})
+}
+
+func writeJSON(out io.Writer, r Record) {
+	byts, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	out.Write(append(byts, '\n'))
+}
+
+func writeLogfmt(out io.Writer, r Record) {
+	var buf bytes.Buffer
+	pair := func(key, val string) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(val))
+	}
+
+	pair("time", r.Time.Format(time.RFC3339Nano))
+	if r.Prefix != "" {
+		pair("prefix", r.Prefix)
+	}
+	pair("request_id", r.RequestID)
+	pair("method", r.Method)
+	pair("path", r.Path)
+	if r.Route != "" {
+		pair("route", r.Route)
+	}
+	pair("status", strconv.Itoa(r.Status))
+	pair("latency", strconv.FormatFloat(r.Latency.Seconds(), 'f', -1, 64))
+	pair("bytes_written", strconv.Itoa(r.BytesWritten))
+	pair("remote_addr", r.RemoteAddr)
+	pair("user_agent", r.UserAgent)
+
+	keys := make([]string, 0, len(r.Headers))
+	for key := range r.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pair("header."+key, r.Headers[key])
+	}
+
+	buf.WriteByte('\n')
+	out.Write(buf.Bytes())
+}
+
+// logfmtValue quotes the value if it's empty or contains spaces, quotes, equal signs or control characters.
+func logfmtValue(val string) string {
+	if val == "" || strings.IndexFunc(val, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\'
+	}) >= 0 {
+		return strconv.Quote(val)
+	}
+	return val
+}
@@ -0,0 +1,74 @@
+package access
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var record = Record{
+	Time:         time.Date(2017, 4, 11, 15, 31, 44, 0, time.UTC),
+	RequestID:    "req-1",
+	Method:       "GET",
+	Path:         "/users/1?q=a b",
+	Route:        "/users/:id",
+	Status:       200,
+	Latency:      1500 * time.Millisecond,
+	BytesWritten: 2,
+	RemoteAddr:   "127.0.0.1",
+	Headers:      map[string]string{"X-Tenant": "t=1", "Accept": "*/*"},
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	writeJSON(&buf, record)
+
+	assert.JSONEq(t, `{
+		"time": "2017-04-11T15:31:44Z",
+		"request_id": "req-1",
+		"method": "GET",
+		"path": "/users/1?q=a b",
+		"route": "/users/:id",
+		"status": 200,
+		"latency": 1.5,
+		"bytes_written": 2,
+		"remote_addr": "127.0.0.1",
+		"user_agent": "",
+		"headers": {"X-Tenant": "t=1", "Accept": "*/*"}
+	}`, buf.String())
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+}
+
+func TestWriteLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	writeLogfmt(&buf, record)
+
+	assert.Equal(t, `time=2017-04-11T15:31:44Z request_id=req-1 method=GET path="/users/1?q=a b" route=/users/:id `+
+		`status=200 latency=1.5 bytes_written=2 remote_addr=127.0.0.1 user_agent="" header.Accept=*/* header.X-Tenant="t=1"`+"\n",
+		buf.String())
+}
+
+func TestWriteText(t *testing.T) {
+	r := record
+	r.Prefix = "api"
+
+	var buf bytes.Buffer
+	writeText(&buf, r, false)
+	assert.Equal(t, "[api] 2017/04/11 - 15:31:44 | req-1 | 200 |          1.5s | 127.0.0.1 |   GET     /users/1?q=a b\n", buf.String())
+
+	buf.Reset()
+	writeText(&buf, r, true)
+	assert.Contains(t, buf.String(), green+" 200 "+reset)
+	assert.Contains(t, buf.String(), blue+"  "+reset+" GET ")
+}
+
+func TestLogfmtValue(t *testing.T) {
+	assert.Equal(t, "plain", logfmtValue("plain"))
+	assert.Equal(t, `""`, logfmtValue(""))
+	assert.Equal(t, `"a b"`, logfmtValue("a b"))
+	assert.Equal(t, `"a=b"`, logfmtValue("a=b"))
+	assert.Equal(t, `"say \"hi\""`, logfmtValue(`say "hi"`))
+	assert.Equal(t, `"line\nbreak"`, logfmtValue("line\nbreak"))
+}
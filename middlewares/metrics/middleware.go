@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sy264115809/golem/utils/metrics"
+
+	iris "gopkg.in/kataras/iris.v6"
+)
+
+const (
+	// ContentType is the content type of the Prometheus text exposition format.
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+	// RouteUnmatched is the default route label of the requests which don't match any registered route.
+	RouteUnmatched = "unmatched"
+)
+
+// New instantiates a middleware which records the count and the latency of http requests into `reg`,
+// metrics.DefaultRegistry will be used if `reg` is nil.
+//
+// The `route` func returns the route label of a request, the path pattern of the matched route (e.g. `/users/:id`)
+// will be used if it's nil. Since every distinct label value creates a new series, the actual path should never
+// be used for routes with parameters.
+//
+// The metrics are:
+// - http_requests_total{route, method, status}
+// - http_request_duration_seconds{route, method, status}
+func New(reg *metrics.Registry, route func(*iris.Context) string) iris.HandlerFunc {
+	if reg == nil {
+		reg = metrics.DefaultRegistry
+	}
+
+	if route == nil {
+		route = RoutePattern
+	}
+
+	var (
+		requests  = reg.NewCounterVec("http_requests_total", "Total number of http requests.", "route", "method", "status")
+		durations = reg.NewHistogramVec("http_request_duration_seconds", "Latency of http requests in seconds.", nil, "route", "method", "status")
+	)
+
+	return func(ctx *iris.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		var (
+			r      = route(ctx)
+			method = ctx.Method()
+			status = strconv.Itoa(ctx.StatusCode())
+		)
+		requests.WithLabelValues(r, method, status).Inc()
+		durations.WithLabelValues(r, method, status).Observe(time.Now().Sub(start).Seconds())
+	}
+}
+
+// RoutePattern returns the path pattern of the route matched by the request, or RouteUnmatched if none.
+//
+// The route is identified by the handler chain which the router assigned to the context, so the registered
+// routes are only visited the first time a chain is seen.
+func RoutePattern(ctx *iris.Context) string {
+	if len(ctx.Middleware) == 0 {
+		return RouteUnmatched
+	}
+
+	chain := &ctx.Middleware[0]
+	if pattern, ok := routePatterns.Load(chain); ok {
+		return pattern.(string)
+	}
+
+	pattern := RouteUnmatched
+	ctx.Framework().Routes().Visit(func(r iris.RouteInfo) {
+		if m := r.Middleware(); len(m) > 0 && &m[0] == chain {
+			pattern = r.Path()
+		}
+	})
+	// the chains of unmatched requests may be built per request, caching them would grow without bound
+	if pattern != RouteUnmatched {
+		routePatterns.Store(chain, pattern)
+	}
+	return pattern
+}
+
+// routePatterns caches the path patterns of the matched routes by the first handler of their handler chains.
+var routePatterns sync.Map
+
+// Handler returns a handler which serves the metrics of `reg` in the Prometheus text exposition format,
+// metrics.DefaultRegistry will be used if `reg` is nil.
+func Handler(reg *metrics.Registry) iris.HandlerFunc {
+	if reg == nil {
+		reg = metrics.DefaultRegistry
+	}
+
+	return func(ctx *iris.Context) {
+		var buf bytes.Buffer
+		if _, err := reg.WriteTo(&buf); err != nil {
+			ctx.WriteHeader(iris.StatusInternalServerError)
+			ctx.WriteString(err.Error())
+			return
+		}
+
+		ctx.SetHeader("Content-Type", ContentType)
+		ctx.WriteHeader(iris.StatusOK)
+		ctx.Write(buf.Bytes())
+	}
+}
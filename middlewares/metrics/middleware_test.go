@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"testing"
+
+	utilmetrics "github.com/sy264115809/golem/utils/metrics"
+
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func TestMiddleware(t *testing.T) {
+	reg := utilmetrics.NewRegistry()
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Use(New(reg, nil))
+	app.Get("/users/:id", func(ctx *iris.Context) {
+		ctx.WriteHeader(iris.StatusOK)
+	})
+	app.Post("/users/:id/avatar", func(ctx *iris.Context) {
+		ctx.WriteHeader(iris.StatusCreated)
+	})
+	app.Get("/static/*file", func(ctx *iris.Context) {
+		ctx.WriteHeader(iris.StatusNotFound)
+	})
+
+	// routes sharing the same handler are told apart by their handler chains
+	ok := func(ctx *iris.Context) {
+		ctx.WriteHeader(iris.StatusOK)
+	}
+	app.Get("/health", ok)
+	app.Get("/ready", ok)
+
+	e := httptest.New(app, t)
+	e.GET("/users/1").Expect().Status(iris.StatusOK)
+	e.GET("/users/2").Expect().Status(iris.StatusOK)
+	e.POST("/users/1/avatar").Expect().Status(iris.StatusCreated)
+	e.GET("/static/js/app.js").Expect().Status(iris.StatusNotFound)
+	e.GET("/health").Expect().Status(iris.StatusOK)
+	e.GET("/ready").Expect().Status(iris.StatusOK)
+	e.GET("/ready").Expect().Status(iris.StatusOK)
+
+	metricsApp := iris.New()
+	metricsApp.Adapt(httprouter.New())
+	metricsApp.Get("/metrics", Handler(reg))
+
+	resp := httptest.New(metricsApp, t).GET("/metrics").Expect()
+	resp.Status(iris.StatusOK)
+	resp.Header("Content-Type").Equal(ContentType)
+	resp.Body().
+		Contains(`http_requests_total{route="/users/:id",method="GET",status="200"} 2`).
+		Contains(`http_requests_total{route="/users/:id/avatar",method="POST",status="201"} 1`).
+		Contains(`http_requests_total{route="/static/*file",method="GET",status="404"} 1`).
+		Contains(`http_requests_total{route="/health",method="GET",status="200"} 1`).
+		Contains(`http_requests_total{route="/ready",method="GET",status="200"} 2`).
+		Contains(`http_request_duration_seconds_count{route="/users/:id",method="GET",status="200"} 2`).
+		NotContains(`/users/1`)
+}
+
+func TestMiddlewareCustomRoute(t *testing.T) {
+	reg := utilmetrics.NewRegistry()
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Use(New(reg, func(ctx *iris.Context) string {
+		return "custom"
+	}))
+	app.Get("/users/:id", func(ctx *iris.Context) {
+		ctx.WriteHeader(iris.StatusOK)
+	})
+	app.Get("/metrics", Handler(reg))
+
+	e := httptest.New(app, t)
+	e.GET("/users/1").Expect().Status(iris.StatusOK)
+	e.GET("/metrics").Expect().Body().Contains(`http_requests_total{route="custom",method="GET",status="200"} 1`)
+}
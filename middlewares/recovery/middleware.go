@@ -1,8 +1,13 @@
 package recovery
 
 import (
+	"net/http"
+	"reflect"
 	"runtime/debug"
 
+	"github.com/sy264115809/golem/controllers"
+	"github.com/sy264115809/golem/middlewares/access"
+
 	iris "gopkg.in/kataras/iris.v6"
 )
 
@@ -11,19 +16,105 @@ type (
 	Logger interface {
 		Errorf(format string, v ...interface{})
 	}
+
+	// Reporter reports the recovered panic value and its stack, e.g. to an error tracking service.
+	Reporter func(ctx *iris.Context, err interface{}, stack []byte)
+
+	// Config is the configuration of recovery middleware.
+	Config struct {
+		// Logger prints the error stack, it's skipped if nil.
+		Logger Logger
+		// Reporter is invoked with every recovered panic, it's skipped if nil.
+		Reporter Reporter
+		// RenderJSON renders a json body like controllers.Response does instead of calling ctx.Panic().
+		RenderJSON bool
+		// Code is the biz code of the json body.
+		Code controllers.Code
+		// Message is the message of the json body.
+		Message string
+		// Repanic are the panic values that should not be recovered, http.ErrAbortHandler by default.
+		Repanic []interface{}
+	}
 )
 
 // New restores the server on internal server errors (panics)
 // receives an Logger logger to print error stack.
 func New(logger Logger) iris.HandlerFunc {
+	return NewWithConfig(Config{
+		Logger: logger,
+	})
+}
+
+// NewWithConfig restores the server on internal server errors (panics) with the specified config.
+func NewWithConfig(c Config) iris.HandlerFunc {
+	if c.Repanic == nil {
+		c.Repanic = []interface{}{http.ErrAbortHandler}
+	}
+
+	base := controllers.New()
+
 	return func(ctx *iris.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Errorf("!!!Recovery from panic\n%s\n%s\n", err, debug.Stack())
-				//ctx.Panic just sends http status 500 by default, but you can change it by: iris.OnPanic(func(c *iris.Context){})
-				ctx.Panic()
+				if repanic(c.Repanic, err) {
+					panic(err)
+				}
+
+				stack := debug.Stack()
+				if c.Logger != nil {
+					c.Logger.Errorf("!!!Recovery from panic\n%s\n%s\n", err, stack)
+				}
+				if c.Reporter != nil {
+					c.Reporter(ctx, err, stack)
+				}
+
+				// the status and the body can't be changed once the body is written
+				if written(ctx) {
+					return
+				}
+
+				if !c.RenderJSON {
+					//ctx.Panic just sends http status 500 by default, but you can change it by: iris.OnPanic(func(c *iris.Context){})
+					ctx.Panic()
+					return
+				}
+
+				resp := base.Response(ctx).WithCode(c.Code)
+				if reqID := access.RequestID(ctx); reqID != "" {
+					resp.WithData("request_id", reqID)
+				}
+
+				message := c.Message
+				if message == "" {
+					message = http.StatusText(http.StatusInternalServerError)
+				}
+				resp.InternalServerError(message)
 			}
 		}()
 		ctx.Next()
 	}
 }
+
+// repanic returns true if err is one of the values, the uncomparable err is never matched.
+func repanic(values []interface{}, err interface{}) bool {
+	if !reflect.TypeOf(err).Comparable() {
+		return false
+	}
+	for _, v := range values {
+		if err == v {
+			return true
+		}
+	}
+	return false
+}
+
+// written returns true if the response body has been written, as far as the response writer reports it.
+func written(ctx *iris.Context) bool {
+	switch w := ctx.ResponseWriter.(type) {
+	case interface{ Written() int }:
+		return w.Written() > 0
+	case interface{ Body() []byte }:
+		return len(w.Body()) > 0
+	}
+	return ctx.ResponseWriter.Header().Get("Content-Length") != ""
+}
@@ -0,0 +1,80 @@
+package recovery
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sy264115809/golem/controllers"
+
+	"github.com/stretchr/testify/assert"
+	iris "gopkg.in/kataras/iris.v6"
+	"gopkg.in/kataras/iris.v6/adaptors/httprouter"
+	"gopkg.in/kataras/iris.v6/httptest"
+)
+
+func TestNewWithConfig(t *testing.T) {
+	var reported []interface{}
+
+	app := iris.New()
+	app.Adapt(httprouter.New())
+	app.Use(NewWithConfig(Config{
+		Reporter: func(ctx *iris.Context, err interface{}, stack []byte) {
+			reported = append(reported, err)
+		},
+		RenderJSON: true,
+		Code:       controllers.NewCode(10001, "panic"),
+	}))
+	app.Get("/string", func(ctx *iris.Context) {
+		panic("boom")
+	})
+	app.Get("/uncomparable", func(ctx *iris.Context) {
+		panic([]string{"boom"})
+	})
+
+	e := httptest.New(app, t)
+	e.GET("/string").Expect().Status(iris.StatusInternalServerError).JSON().Object().
+		ValueEqual("code", 10001).
+		ValueEqual("code_text", "panic").
+		ValueEqual("message", http.StatusText(http.StatusInternalServerError))
+	e.GET("/uncomparable").Expect().Status(iris.StatusInternalServerError).JSON().Object().
+		ValueEqual("code", 10001)
+
+	assert.Equal(t, []interface{}{"boom", []string{"boom"}}, reported)
+}
+
+func TestRepanic(t *testing.T) {
+	values := []interface{}{http.ErrAbortHandler, "fatal"}
+
+	assert.True(t, repanic(values, http.ErrAbortHandler))
+	assert.True(t, repanic(values, "fatal"))
+	assert.False(t, repanic(values, "boom"))
+	assert.False(t, repanic(values, []string{"fatal"}))
+	assert.False(t, repanic([]interface{}{[]string{"fatal"}}, []string{"fatal"}))
+	assert.False(t, repanic(nil, http.ErrAbortHandler))
+}
+
+type countingWriter struct {
+	iris.ResponseWriter
+	n int
+}
+
+func (w *countingWriter) Written() int {
+	return w.n
+}
+
+type headerWriter struct {
+	iris.ResponseWriter
+	header http.Header
+}
+
+func (w *headerWriter) Header() http.Header {
+	return w.header
+}
+
+func TestWritten(t *testing.T) {
+	assert.False(t, written(&iris.Context{ResponseWriter: &countingWriter{}}))
+	assert.True(t, written(&iris.Context{ResponseWriter: &countingWriter{n: 2}}))
+
+	assert.False(t, written(&iris.Context{ResponseWriter: &headerWriter{header: http.Header{}}}))
+	assert.True(t, written(&iris.Context{ResponseWriter: &headerWriter{header: http.Header{"Content-Length": {"2"}}}}))
+}
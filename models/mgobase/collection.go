@@ -1,6 +1,9 @@
 package mgobase
 
 import (
+	"context"
+	"net"
+	"reflect"
 	"sync"
 	"time"
 
@@ -18,6 +21,9 @@ type (
 		ensureIndexed   bool
 		ensureIndexLock *sync.Mutex
 
+		hooks   *hookChain
+		dbHooks *hookChain
+
 		debug  bool
 		logger logger
 	}
@@ -82,73 +88,234 @@ func logSlowQuery(from, to time.Time) {
 
 // Invoke invokes a callback function with a session created from session factory.
 func (c *Collection) Invoke(fn func(*mgo.Collection) error) error {
-	err := c.ensureIndex()
+	return c.InvokeCtx(context.Background(), fn)
+}
+
+// InvokeCtx works just like Invoke, but it returns ErrCanceled or ErrDeadlineExceeded as soon as the ctx is done.
+// If the ctx has a deadline, it will be applied as the socket timeout of the session.
+//
+// InvokeCtx doesn't wait for the callback once the ctx is done, the callback keeps running in background with
+// its own session until the pending operation returns, so it must not write anything the caller reads then.
+func (c *Collection) InvokeCtx(ctx context.Context, fn func(*mgo.Collection) error) error {
+	return c.invoke(ctx, OpInvoke, nil, fn)
+}
+
+// Use registers hooks which will be notified around every operation of this collection,
+// after the hooks registered on the database the collection belongs to.
+func (c *Collection) Use(hooks ...QueryHook) *Collection {
+	if c.hooks == nil {
+		c.hooks = newHookChain()
+	}
+	c.hooks.add(hooks...)
+	return c
+}
+
+func (c *Collection) invoke(ctx context.Context, op string, query interface{}, fn func(*mgo.Collection) error) (err error) {
+	dbHooks, colHooks := c.dbHooks.list(), c.hooks.list()
+	hooks := make([]QueryHook, 0, len(dbHooks)+len(colHooks))
+	hooks = append(append(hooks, dbHooks...), colHooks...)
+	event := &QueryEvent{
+		Collection: c.colName,
+		Operation:  op,
+		Query:      query,
+	}
+
+	ctx = before(ctx, hooks, event)
+	start := time.Now()
+	defer func() {
+		end := time.Now()
+		logSlowQuery(start, end)
+
+		event.Duration = end.Sub(start)
+		event.Err = err
+		after(ctx, hooks, event)
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return parseContextError(err)
+	}
+
+	err = c.ensureIndex()
 	if err != nil {
 		return err
 	}
 
-	sess := c.sessionFactory()
-	defer sess.Close()
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline && time.Until(deadline) <= 0 {
+		return ErrDeadlineExceeded
+	}
 
+	sess := c.sessionFactory()
+	if hasDeadline {
+		sess.SetSocketTimeout(time.Until(deadline))
+	}
 	col := sess.DB(c.dbName).C(c.colName)
 
-	start := time.Now()
-	err = fn(col)
-	time.Now().Sub(start)
+	if ctx.Done() == nil {
+		defer sess.Close()
+		err = fn(col)
+	} else {
+		// the session is closed by the callback's goroutine, since it may still be used after the ctx is done.
+		done := make(chan error, 1)
+		go func() {
+			defer sess.Close()
+			done <- fn(col)
+		}()
+
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			return parseContextError(ctx.Err())
+		}
+	}
 
+	if cbErr, ok := err.(callbackError); ok {
+		return cbErr.err
+	}
+	if hasDeadline && isTimeout(err) {
+		return ErrDeadlineExceeded
+	}
 	return parseMgoError(err)
 }
 
+// callbackError wraps the error returned by the callback of the caller, e.g. the one of ForEach,
+// so invoke returns it as is rather than mapping it as an error of db.
+type callbackError struct {
+	err error
+}
+
+func (e callbackError) Error() string {
+	return e.err.Error()
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// invokeInto works just like invoke, but fn unmarshals the results into a private copy of out,
+// which is copied to out only if fn succeeds, so out is never written once invoke returns because the ctx is done.
+func (c *Collection) invokeInto(ctx context.Context, op string, query, out interface{}, fn func(col *mgo.Collection, out interface{}) error) error {
+	result, commit := shadow(out)
+	err := c.invoke(ctx, op, query, func(col *mgo.Collection) error {
+		return fn(col, result)
+	})
+	if err == nil {
+		commit()
+	}
+	return err
+}
+
+// shadow returns a new value of the same type as out to unmarshal into, and the func copying it to out.
+// out is returned as is if it's neither a pointer nor a map, so mgo could report it.
+func shadow(out interface{}) (interface{}, func()) {
+	v := reflect.ValueOf(out)
+	switch {
+	case v.Kind() == reflect.Ptr && !v.IsNil():
+		s := reflect.New(v.Elem().Type())
+		return s.Interface(), func() {
+			v.Elem().Set(s.Elem())
+		}
+	case v.Kind() == reflect.Map && !v.IsNil():
+		s := reflect.MakeMap(v.Type())
+		return s.Interface(), func() {
+			for _, k := range s.MapKeys() {
+				v.SetMapIndex(k, s.MapIndex(k))
+			}
+		}
+	}
+	return out, func() {}
+}
+
 // Insert inserts one or more documents.
 func (c *Collection) Insert(models ...interface{}) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.InsertCtx(context.Background(), models...)
+}
+
+// InsertCtx is the context-aware version of Insert.
+func (c *Collection) InsertCtx(ctx context.Context, models ...interface{}) error {
+	return c.invoke(ctx, OpInsert, nil, func(col *mgo.Collection) error {
 		return col.Insert(models...)
 	})
 }
 
 // UpsertByObjectID upserts documents by given object id.
 func (c *Collection) UpsertByObjectID(id bson.ObjectId, update interface{}) (info *mgo.ChangeInfo, err error) {
+	return c.UpsertByObjectIDCtx(context.Background(), id, update)
+}
+
+// UpsertByObjectIDCtx is the context-aware version of UpsertByObjectID.
+func (c *Collection) UpsertByObjectIDCtx(ctx context.Context, id bson.ObjectId, update interface{}) (info *mgo.ChangeInfo, err error) {
 	if !bson.IsObjectIdHex(id.Hex()) {
 		return nil, ErrInvalidID
 	}
 
-	err = c.Invoke(func(col *mgo.Collection) error {
-		info, err = col.UpsertId(id, update)
+	var changed *mgo.ChangeInfo
+	err = c.invoke(ctx, OpUpsert, bson.M{"_id": id}, func(col *mgo.Collection) error {
+		var err error
+		changed, err = col.UpsertId(id, update)
 		return err
 	})
-
-	return
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
 }
 
 // Upsert upserts documents by query selector.
 func (c *Collection) Upsert(selector, update interface{}) (info *mgo.ChangeInfo, err error) {
-	err = c.Invoke(func(col *mgo.Collection) error {
-		info, err = col.Upsert(selector, update)
+	return c.UpsertCtx(context.Background(), selector, update)
+}
+
+// UpsertCtx is the context-aware version of Upsert.
+func (c *Collection) UpsertCtx(ctx context.Context, selector, update interface{}) (info *mgo.ChangeInfo, err error) {
+	var changed *mgo.ChangeInfo
+	err = c.invoke(ctx, OpUpsert, selector, func(col *mgo.Collection) error {
+		var err error
+		changed, err = col.Upsert(selector, update)
 		return err
 	})
-	return
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
 }
 
 // UpdateByObjectID updates a single document by object id.
 func (c *Collection) UpdateByObjectID(id bson.ObjectId, update interface{}) error {
+	return c.UpdateByObjectIDCtx(context.Background(), id, update)
+}
+
+// UpdateByObjectIDCtx is the context-aware version of UpdateByObjectID.
+func (c *Collection) UpdateByObjectIDCtx(ctx context.Context, id bson.ObjectId, update interface{}) error {
 	if !bson.IsObjectIdHex(id.Hex()) {
 		return ErrInvalidID
 	}
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.invoke(ctx, OpUpdate, bson.M{"_id": id}, func(col *mgo.Collection) error {
 		return col.UpdateId(id, update)
 	})
 }
 
 // Update updates a single document by query selector.
 func (c *Collection) Update(selector, update interface{}) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.UpdateCtx(context.Background(), selector, update)
+}
+
+// UpdateCtx is the context-aware version of Update.
+func (c *Collection) UpdateCtx(ctx context.Context, selector, update interface{}) error {
+	return c.invoke(ctx, OpUpdate, selector, func(col *mgo.Collection) error {
 		return col.Update(selector, update)
 	})
 }
 
 // UpdateAll updates all documents match the query selector.
 func (c *Collection) UpdateAll(selector, update interface{}) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.UpdateAllCtx(context.Background(), selector, update)
+}
+
+// UpdateAllCtx is the context-aware version of UpdateAll.
+func (c *Collection) UpdateAllCtx(ctx context.Context, selector, update interface{}) error {
+	return c.invoke(ctx, OpUpdateAll, selector, func(col *mgo.Collection) error {
 		_, err := col.UpdateAll(selector, update)
 		return err
 	})
@@ -156,24 +323,39 @@ func (c *Collection) UpdateAll(selector, update interface{}) error {
 
 // UpdateSetByObjectID updates a single document with $set operator by object id.
 func (c *Collection) UpdateSetByObjectID(id bson.ObjectId, update interface{}) error {
+	return c.UpdateSetByObjectIDCtx(context.Background(), id, update)
+}
+
+// UpdateSetByObjectIDCtx is the context-aware version of UpdateSetByObjectID.
+func (c *Collection) UpdateSetByObjectIDCtx(ctx context.Context, id bson.ObjectId, update interface{}) error {
 	if !bson.IsObjectIdHex(id.Hex()) {
 		return ErrInvalidID
 	}
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.invoke(ctx, OpUpdate, bson.M{"_id": id}, func(col *mgo.Collection) error {
 		return col.UpdateId(id, bson.M{"$set": update})
 	})
 }
 
 // UpdateSet updates a single document with $set operator by query selector.
 func (c *Collection) UpdateSet(selector, update interface{}) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.UpdateSetCtx(context.Background(), selector, update)
+}
+
+// UpdateSetCtx is the context-aware version of UpdateSet.
+func (c *Collection) UpdateSetCtx(ctx context.Context, selector, update interface{}) error {
+	return c.invoke(ctx, OpUpdate, selector, func(col *mgo.Collection) error {
 		return col.Update(selector, bson.M{"$set": update})
 	})
 }
 
 // UpdateSetAll updates all documents match the query selector with $set operator.
 func (c *Collection) UpdateSetAll(selector, update interface{}) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.UpdateSetAllCtx(context.Background(), selector, update)
+}
+
+// UpdateSetAllCtx is the context-aware version of UpdateSetAll.
+func (c *Collection) UpdateSetAllCtx(ctx context.Context, selector, update interface{}) error {
+	return c.invoke(ctx, OpUpdateAll, selector, func(col *mgo.Collection) error {
 		_, err := col.UpdateAll(selector, bson.M{"$set": update})
 		return err
 	})
@@ -181,32 +363,52 @@ func (c *Collection) UpdateSetAll(selector, update interface{}) error {
 
 // Remove removes a single document by query selector.
 func (c *Collection) Remove(selector interface{}) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.RemoveCtx(context.Background(), selector)
+}
+
+// RemoveCtx is the context-aware version of Remove.
+func (c *Collection) RemoveCtx(ctx context.Context, selector interface{}) error {
+	return c.invoke(ctx, OpRemove, selector, func(col *mgo.Collection) error {
 		return col.Remove(selector)
 	})
 }
 
 // RemoveByID removes a single document by object id in string form.
 func (c *Collection) RemoveByID(id string) error {
+	return c.RemoveByIDCtx(context.Background(), id)
+}
+
+// RemoveByIDCtx is the context-aware version of RemoveByID.
+func (c *Collection) RemoveByIDCtx(ctx context.Context, id string) error {
 	if !bson.IsObjectIdHex(id) {
 		return ErrInvalidID
 	}
-	return c.RemoveByObjectID(bson.ObjectIdHex(id))
+	return c.RemoveByObjectIDCtx(ctx, bson.ObjectIdHex(id))
 }
 
 // RemoveByObjectID removes a single document by object id.
 func (c *Collection) RemoveByObjectID(id bson.ObjectId) error {
+	return c.RemoveByObjectIDCtx(context.Background(), id)
+}
+
+// RemoveByObjectIDCtx is the context-aware version of RemoveByObjectID.
+func (c *Collection) RemoveByObjectIDCtx(ctx context.Context, id bson.ObjectId) error {
 	if !bson.IsObjectIdHex(id.Hex()) {
 		return ErrInvalidID
 	}
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.invoke(ctx, OpRemove, bson.M{"_id": id}, func(col *mgo.Collection) error {
 		return col.RemoveId(id)
 	})
 }
 
 // RemoveAll removes all documents match the query selector.
 func (c *Collection) RemoveAll(selector interface{}) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.RemoveAllCtx(context.Background(), selector)
+}
+
+// RemoveAllCtx is the context-aware version of RemoveAll.
+func (c *Collection) RemoveAllCtx(ctx context.Context, selector interface{}) error {
+	return c.invoke(ctx, OpRemoveAll, selector, func(col *mgo.Collection) error {
 		_, err := col.RemoveAll(selector)
 		return err
 	})
@@ -214,25 +416,40 @@ func (c *Collection) RemoveAll(selector interface{}) error {
 
 // Find finds a single document by given query and sort conditions if exist.
 func (c *Collection) Find(query, model interface{}, sorts ...string) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.FindCtx(context.Background(), query, model, sorts...)
+}
+
+// FindCtx is the context-aware version of Find.
+func (c *Collection) FindCtx(ctx context.Context, query, model interface{}, sorts ...string) error {
+	return c.invokeInto(ctx, OpFind, query, model, func(col *mgo.Collection, model interface{}) error {
 		return col.Find(query).Sort(sorts...).One(model)
 	})
 }
 
 // FindByID finds a single document by object id in string form.
 func (c *Collection) FindByID(id string, model interface{}) error {
+	return c.FindByIDCtx(context.Background(), id, model)
+}
+
+// FindByIDCtx is the context-aware version of FindByID.
+func (c *Collection) FindByIDCtx(ctx context.Context, id string, model interface{}) error {
 	if !bson.IsObjectIdHex(id) {
 		return ErrInvalidID
 	}
-	return c.FindByObjectID(bson.ObjectIdHex(id), model)
+	return c.FindByObjectIDCtx(ctx, bson.ObjectIdHex(id), model)
 }
 
 // FindByObjectID finds a single document by object id.
 func (c *Collection) FindByObjectID(id bson.ObjectId, model interface{}) error {
+	return c.FindByObjectIDCtx(context.Background(), id, model)
+}
+
+// FindByObjectIDCtx is the context-aware version of FindByObjectID.
+func (c *Collection) FindByObjectIDCtx(ctx context.Context, id bson.ObjectId, model interface{}) error {
 	if !bson.IsObjectIdHex(id.Hex()) {
 		return ErrInvalidID
 	}
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.invokeInto(ctx, OpFind, bson.M{"_id": id}, model, func(col *mgo.Collection, model interface{}) error {
 		return col.FindId(id).One(model)
 	})
 }
@@ -245,19 +462,29 @@ func (c *Collection) FindByObjectID(id bson.ObjectId, model interface{}) error {
 //
 // each elements of `sorts` should be nonempty string if the `sorts` are provided.
 func (c *Collection) FindAll(query, selector, models interface{}, skip, limit int, sorts ...string) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.FindAllCtx(context.Background(), query, selector, models, skip, limit, sorts...)
+}
+
+// FindAllCtx is the context-aware version of FindAll.
+func (c *Collection) FindAllCtx(ctx context.Context, query, selector, models interface{}, skip, limit int, sorts ...string) error {
+	return c.invokeInto(ctx, OpFindAll, query, models, func(col *mgo.Collection, models interface{}) error {
 		return col.Find(query).Select(selector).Skip(skip).Limit(limit).Sort(sorts...).All(models)
 	})
 }
 
 // FindAllWithPagination works just like FindAll, but it returns a paginater to indicate the informations about pagination.
 func (c *Collection) FindAllWithPagination(query, selector, models interface{}, skip, limit int, sorts ...string) (Paginater, error) {
-	err := c.FindAll(query, selector, models, skip, limit, sorts...)
+	return c.FindAllWithPaginationCtx(context.Background(), query, selector, models, skip, limit, sorts...)
+}
+
+// FindAllWithPaginationCtx is the context-aware version of FindAllWithPagination.
+func (c *Collection) FindAllWithPaginationCtx(ctx context.Context, query, selector, models interface{}, skip, limit int, sorts ...string) (Paginater, error) {
+	err := c.FindAllCtx(ctx, query, selector, models, skip, limit, sorts...)
 	if err != nil {
 		return nil, err
 	}
 
-	count, err := c.Count(query)
+	count, err := c.CountCtx(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -269,32 +496,62 @@ func (c *Collection) FindAllWithPagination(query, selector, models interface{},
 //
 // See `Marker` also.
 func (c *Collection) FindAllWithMarker(query, selector, models interface{}, marker Marker, limit int) (prev, next interface{}, err error) {
-	c.Invoke(func(col *mgo.Collection) error {
-		prev, next, err = marker.List(col, query, selector, models, limit)
+	return c.FindAllWithMarkerCtx(context.Background(), query, selector, models, marker, limit)
+}
+
+// FindAllWithMarkerCtx is the context-aware version of FindAllWithMarker.
+func (c *Collection) FindAllWithMarkerCtx(ctx context.Context, query, selector, models interface{}, marker Marker, limit int) (prev, next interface{}, err error) {
+	var prevMark, nextMark interface{}
+	err = c.invokeInto(ctx, OpFindAll, query, models, func(col *mgo.Collection, models interface{}) error {
+		var err error
+		prevMark, nextMark, err = marker.List(col, query, selector, models, limit)
 		return err
 	})
-	return
+	if err != nil {
+		return nil, nil, err
+	}
+	return prevMark, nextMark, nil
 }
 
 // Distinct unmarshals into result the list of distinct values for the given key.
 func (c *Collection) Distinct(query, models interface{}, key string) error {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.DistinctCtx(context.Background(), query, models, key)
+}
+
+// DistinctCtx is the context-aware version of Distinct.
+func (c *Collection) DistinctCtx(ctx context.Context, query, models interface{}, key string) error {
+	return c.invokeInto(ctx, OpDistinct, query, models, func(col *mgo.Collection, models interface{}) error {
 		return col.Find(query).Distinct(key, models)
 	})
 }
 
 // Count returns the total number of documents by query.
 func (c *Collection) Count(query interface{}) (n int, err error) {
-	err = c.Invoke(func(col *mgo.Collection) error {
-		n, err = col.Find(query).Count()
+	return c.CountCtx(context.Background(), query)
+}
+
+// CountCtx is the context-aware version of Count.
+func (c *Collection) CountCtx(ctx context.Context, query interface{}) (n int, err error) {
+	var count int
+	err = c.invoke(ctx, OpCount, query, func(col *mgo.Collection) error {
+		var err error
+		count, err = col.Find(query).Count()
 		return err
 	})
-	return
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
 // Drop drops the collection.
 func (c *Collection) Drop() (err error) {
-	return c.Invoke(func(col *mgo.Collection) error {
+	return c.DropCtx(context.Background())
+}
+
+// DropCtx is the context-aware version of Drop.
+func (c *Collection) DropCtx(ctx context.Context) (err error) {
+	return c.invoke(ctx, OpDrop, nil, func(col *mgo.Collection) error {
 		return col.DropCollection()
 	})
 }
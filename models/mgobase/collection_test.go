@@ -0,0 +1,247 @@
+package mgobase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestOperationsWithDoneContext(t *testing.T) {
+	var events []*QueryEvent
+	hook := QueryHookFuncs{
+		After: func(ctx context.Context, e *QueryEvent) {
+			events = append(events, e)
+		},
+	}
+	col := NewDatabase().C("users").Use(hook)
+	repo := NewRepository[bson.M](col)
+
+	id := bson.ObjectIdHex("58db2700cf2f6715b00021a7")
+	pipeline := NewPipeline().Limit(1)
+	noop := func(*mgo.Iter) error { return nil }
+
+	type testcase struct {
+		desc  string
+		op    string
+		query interface{}
+		call  func(ctx context.Context) error
+	}
+
+	testcases := []testcase{
+		{"invoke", OpInvoke, nil, func(ctx context.Context) error {
+			return col.InvokeCtx(ctx, nil)
+		}},
+		{"count", OpCount, "query", func(ctx context.Context) error {
+			_, err := col.CountCtx(ctx, "query")
+			return err
+		}},
+		{"query all", OpFindAll, "query", func(ctx context.Context) error {
+			return col.Query("query").Context(ctx).All(nil)
+		}},
+		{"query one", OpFind, "query", func(ctx context.Context) error {
+			return col.Query("query").Context(ctx).One(nil)
+		}},
+		{"query count", OpCount, "query", func(ctx context.Context) error {
+			_, err := col.Query("query").Context(ctx).Count()
+			return err
+		}},
+		{"query iter", OpIter, "query", func(ctx context.Context) error {
+			return col.Query("query").Context(ctx).Iter(noop)
+		}},
+		{"typed query all", OpFindAll, "query", func(ctx context.Context) error {
+			_, err := repo.Query("query").Context(ctx).Limit(1).All()
+			return err
+		}},
+		{"for each", OpIter, bson.M{"_id": bson.M{"$gt": id}}, func(ctx context.Context) error {
+			var model bson.M
+			lastID, err := col.ForEachCtx(ctx, nil, &model, IterOptions{ResumeAfter: id}, nil)
+			assert.Equal(t, id, lastID)
+			return err
+		}},
+		{"typed for each", OpIter, nil, func(ctx context.Context) error {
+			lastID, err := repo.ForEachCtx(ctx, nil, IterOptions{}, nil)
+			assert.Nil(t, lastID)
+			return err
+		}},
+		{"aggregate", OpAggregate, pipeline.Stages(), func(ctx context.Context) error {
+			return col.AggregateCtx(ctx, pipeline, nil, AggregateOptions{AllowDiskUse: true})
+		}},
+		{"aggregate iter", OpAggregate, pipeline.Stages(), func(ctx context.Context) error {
+			return col.AggregateIterCtx(ctx, pipeline, AggregateOptions{}, noop)
+		}},
+		{"aggregate with pagination", OpAggregate, pipeline.paginate(0, 10, nil).Stages(), func(ctx context.Context) error {
+			p, err := col.AggregateWithPaginationCtx(ctx, pipeline, nil, 0, 10, AggregateOptions{})
+			assert.Nil(t, p)
+			return err
+		}},
+		{"typed find all with facet pagination", OpAggregate, NewPipeline().Match(bson.M{}).Sort("-_id").paginate(0, 10, nil).Stages(), func(ctx context.Context) error {
+			page, err := repo.FindAllWithFacetPaginationCtx(ctx, nil, nil, 0, 10, "-_id")
+			assert.Nil(t, page)
+			return err
+		}},
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	for _, tc := range testcases {
+		for _, done := range []struct {
+			ctx context.Context
+			err error
+		}{{canceled, ErrCanceled}, {expired, ErrDeadlineExceeded}} {
+			events = nil
+			assert.Equal(t, done.err, tc.call(done.ctx), tc.desc)
+			if assert.Len(t, events, 1, tc.desc) {
+				assert.Equal(t, tc.op, events[0].Operation, tc.desc)
+				assert.Equal(t, tc.query, events[0].Query, tc.desc)
+				assert.Equal(t, done.err, events[0].Err, tc.desc)
+			}
+		}
+	}
+
+	var model bson.M
+	_, err := col.ForEachCtx(canceled, nil, model, IterOptions{}, nil)
+	assert.EqualError(t, err, "model must be a non-nil pointer, got bson.M")
+}
+
+func TestInvokeHooks(t *testing.T) {
+	var calls []string
+	hook := func(name string) QueryHook {
+		return QueryHookFuncs{
+			Before: func(ctx context.Context, e *QueryEvent) context.Context {
+				calls = append(calls, "before "+name)
+				return ctx
+			},
+			After: func(ctx context.Context, e *QueryEvent) {
+				calls = append(calls, "after "+name)
+				assert.Equal(t, "users", e.Collection)
+				assert.Equal(t, OpCount, e.Operation)
+				assert.Equal(t, "query", e.Query)
+				assert.Equal(t, ErrCanceled, e.Err)
+			},
+		}
+	}
+
+	db := NewDatabase().Use(hook("db"))
+	col := db.C("users").Use(hook("col"))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := col.CountCtx(canceled, "query")
+	assert.Equal(t, ErrCanceled, err)
+	assert.Equal(t, []string{"before db", "before col", "after col", "after db"}, calls)
+}
+
+func TestInvokeCtxReturnsOnceDone(t *testing.T) {
+	col := &Collection{
+		sessionFactory: func() *mgo.Session { return &mgo.Session{} },
+		ensureIndexed:  true,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	var model struct{ Name string }
+	err := col.invokeInto(ctx, OpFind, nil, &model, func(_ *mgo.Collection, out interface{}) error {
+		close(started)
+		<-release
+		// written after invokeInto returns, so it must not be the model of the caller.
+		out.(*struct{ Name string }).Name = "written after the ctx is done"
+		return nil
+	})
+
+	assert.Equal(t, ErrCanceled, err)
+	assert.Empty(t, model.Name)
+}
+
+func TestInvokeCallbackError(t *testing.T) {
+	col := &Collection{
+		sessionFactory: func() *mgo.Session { return &mgo.Session{} },
+		ensureIndexed:  true,
+	}
+
+	// the errors of db are mapped, but the ones of the caller's callback are not.
+	assert.Equal(t, ErrNotFound, col.Invoke(func(*mgo.Collection) error { return mgo.ErrNotFound }))
+	assert.Equal(t, mgo.ErrNotFound, col.Invoke(func(*mgo.Collection) error { return callbackError{mgo.ErrNotFound} }))
+}
+
+func TestShadow(t *testing.T) {
+	var models []string
+	out, commit := shadow(&models)
+	*out.(*[]string) = []string{"a", "b"}
+	assert.Nil(t, models)
+	commit()
+	assert.Equal(t, []string{"a", "b"}, models)
+
+	doc := bson.M{"a": 1}
+	out, commit = shadow(doc)
+	out.(bson.M)["b"] = 2
+	assert.Equal(t, bson.M{"a": 1}, doc)
+	commit()
+	assert.Equal(t, bson.M{"a": 1, "b": 2}, doc)
+
+	out, commit = shadow(nil)
+	commit()
+	assert.Nil(t, out)
+}
+
+// testCollection returns the collection of the test db, which is dropped once the test finishes.
+func testCollection(t *testing.T, name string, indexes ...Index) *Collection {
+	sess, err := mgo.DialWithTimeout(dsn, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sess.DB("golem_test").C(name).DropCollection()
+	t.Cleanup(func() {
+		sess.DB("golem_test").C(name).DropCollection()
+		sess.Close()
+	})
+
+	return &Collection{
+		sessionFactory:  sess.Copy,
+		dbName:          "golem_test",
+		colName:         name,
+		indexes:         indexes,
+		ensureIndexLock: &sync.Mutex{},
+		hooks:           newHookChain(),
+	}
+}
+
+func TestInvokeCtxWithSlowQuery(t *testing.T) {
+	col := testCollection(t, "slow")
+	assert.NoError(t, col.Insert(bson.M{"name": "a"}))
+
+	// the query takes 2s on the server for the only document.
+	slow := bson.M{"$where": "sleep(2000) || true"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	var doc bson.M
+	assert.Equal(t, ErrCanceled, col.FindCtx(ctx, slow, &doc))
+	assert.True(t, time.Since(start) < time.Second, "canceled after %s", time.Since(start))
+	assert.Nil(t, doc)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err := col.CountCtx(ctx, slow)
+	assert.Equal(t, ErrDeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second, "deadline exceeded after %s", time.Since(start))
+
+	assert.NoError(t, col.FindCtx(context.Background(), bson.M{"name": "a"}, &doc))
+	assert.Equal(t, "a", doc["name"])
+}
@@ -11,12 +11,15 @@ import (
 type Database struct {
 	session *mgo.Session
 	dbName  string
+	hooks   *hookChain
 }
 
 // NewDatabase returns a Database instance.
 // You should initiate it by calling `InitWithURL` or `InitWithDialInfo`.
 func NewDatabase() *Database {
-	return &Database{}
+	return &Database{
+		hooks: newHookChain(),
+	}
 }
 
 // InitWithURL initiates db instance by given url.
@@ -64,7 +67,18 @@ func (d *Database) C(name string, indexes ...Index) *Collection {
 		colName:         name,
 		indexes:         indexes,
 		ensureIndexLock: &sync.Mutex{},
+		hooks:           newHookChain(),
+		dbHooks:         d.hooks,
+	}
+}
+
+// Use registers hooks which will be notified around every operation of the collections created by this db.
+func (d *Database) Use(hooks ...QueryHook) *Database {
+	if d.hooks == nil {
+		d.hooks = newHookChain()
 	}
+	d.hooks.add(hooks...)
+	return d
 }
 
 // Copy copies a new db instance from this instance.
@@ -74,6 +88,7 @@ func (d *Database) Copy() *Database {
 	return &Database{
 		session: d.session.Copy(),
 		dbName:  d.dbName,
+		hooks:   newHookChain(d.hooks.list()...),
 	}
 }
 
@@ -82,6 +97,7 @@ func (d *Database) Clone() *Database {
 	return &Database{
 		session: d.session.Clone(),
 		dbName:  d.dbName,
+		hooks:   newHookChain(d.hooks.list()...),
 	}
 }
 
@@ -1,6 +1,7 @@
 package mgobase
 
 import (
+	"context"
 	"fmt"
 
 	mgo "gopkg.in/mgo.v2"
@@ -15,6 +16,12 @@ const (
 	ErrDuplicateKey
 	// ErrNotConnected represents can't not connect to db.
 	ErrNotConnected
+	// ErrDeadlineExceeded represents the operation is aborted because the deadline of its context is exceeded.
+	ErrDeadlineExceeded
+	// ErrCanceled represents the operation is aborted because its context is canceled.
+	ErrCanceled
+	// ErrStopIteration could be returned by the callback of ForEach to stop the iteration without error.
+	ErrStopIteration
 )
 
 // ModelError is the mgobase package level error type.
@@ -30,6 +37,12 @@ func (e ModelError) Error() string {
 		return "not found"
 	case ErrNotConnected:
 		return "db is not connected"
+	case ErrDeadlineExceeded:
+		return "deadline exceeded"
+	case ErrCanceled:
+		return "operation canceled"
+	case ErrStopIteration:
+		return "iteration stopped"
 	default:
 		return fmt.Sprintf("undefined model error, number: %d", int(e))
 	}
@@ -46,3 +59,13 @@ func parseMgoError(err error) error {
 
 	return err
 }
+
+func parseContextError(err error) error {
+	switch err {
+	case context.DeadlineExceeded:
+		return ErrDeadlineExceeded
+	case context.Canceled:
+		return ErrCanceled
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package mgobase
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The operation names reported to the query hooks.
+const (
+	OpInvoke    = "invoke"
+	OpInsert    = "insert"
+	OpUpsert    = "upsert"
+	OpUpdate    = "update"
+	OpUpdateAll = "update_all"
+	OpRemove    = "remove"
+	OpRemoveAll = "remove_all"
+	OpFind      = "find"
+	OpFindAll   = "find_all"
+	OpCount     = "count"
+	OpDistinct  = "distinct"
+	OpIter      = "iter"
+	OpAggregate = "aggregate"
+	OpDrop      = "drop"
+)
+
+type (
+	// QueryEvent describes an operation invoked by a Collection.
+	QueryEvent struct {
+		Collection string
+		Operation  string
+		Query      interface{}
+
+		// Duration and Err are only available after the operation is done.
+		Duration time.Duration
+		Err      error
+	}
+
+	// QueryHook is notified before and after every operation invoked by a Collection.
+	// The context returned by BeforeQuery is used for the operation and the AfterQuery,
+	// so that a hook can carry its own values (e.g. a tracing span) through.
+	QueryHook interface {
+		BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+		AfterQuery(ctx context.Context, event *QueryEvent)
+	}
+
+	// QueryHookFuncs is an adapter to allow the use of ordinary functions as QueryHook.
+	// A nil function is just skipped.
+	QueryHookFuncs struct {
+		Before func(ctx context.Context, event *QueryEvent) context.Context
+		After  func(ctx context.Context, event *QueryEvent)
+	}
+)
+
+var _ QueryHook = QueryHookFuncs{}
+
+// BeforeQuery calls f.Before if it's not nil.
+func (f QueryHookFuncs) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	if f.Before != nil {
+		if c := f.Before(ctx, event); c != nil {
+			return c
+		}
+	}
+	return ctx
+}
+
+// AfterQuery calls f.After if it's not nil.
+func (f QueryHookFuncs) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if f.After != nil {
+		f.After(ctx, event)
+	}
+}
+
+type hookChain struct {
+	lock  sync.RWMutex
+	hooks []QueryHook
+}
+
+func newHookChain(hooks ...QueryHook) *hookChain {
+	return &hookChain{
+		hooks: append([]QueryHook(nil), hooks...),
+	}
+}
+
+func (hc *hookChain) add(hooks ...QueryHook) {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+	hc.hooks = append(hc.hooks, hooks...)
+}
+
+func (hc *hookChain) list() []QueryHook {
+	if hc == nil {
+		return nil
+	}
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+	return hc.hooks
+}
+
+// before calls the BeforeQuery of hooks in order.
+func before(ctx context.Context, hooks []QueryHook, event *QueryEvent) context.Context {
+	for _, hook := range hooks {
+		ctx = hook.BeforeQuery(ctx, event)
+	}
+	return ctx
+}
+
+// after calls the AfterQuery of hooks in reverse order, so the first hook wraps all the others.
+func after(ctx context.Context, hooks []QueryHook, event *QueryEvent) {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].AfterQuery(ctx, event)
+	}
+}
@@ -0,0 +1,134 @@
+package mgobase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// IterOptions are the options of ForEach.
+type IterOptions struct {
+	// Selector is used for project fields if no nil.
+	Selector interface{}
+	// BatchSize is the number of documents fetched from db in a round trip, the default of db is used if it's 0.
+	BatchSize int
+	// ResumeAfter resumes the iteration after the document of the `_id`, which is usually the one returned by
+	// an aborted ForEach.
+	ResumeAfter interface{}
+}
+
+// ForEach iterates the documents match the query in `_id` order without loading them all into memory.
+// Every document is unmarshalled into model, which must be a pointer, and then fn is called.
+//
+// The session is kept open only while iterating. The iteration is aborted once fn returns an error,
+// which is returned as is, except ErrStopIteration which stops the iteration without error.
+//
+// lastID is the `_id` of the last document fn succeeded on, or opts.ResumeAfter if there is none,
+// so the iteration could be resumed from it by `IterOptions.ResumeAfter` even if it's aborted.
+func (c *Collection) ForEach(query, model interface{}, opts IterOptions, fn func() error) (lastID interface{}, err error) {
+	return c.ForEachCtx(context.Background(), query, model, opts, fn)
+}
+
+// ForEachCtx is the context-aware version of ForEach, the iteration is aborted once the ctx is done.
+// Once the ctx is done, it doesn't wait for the documents being fetched but only the running fn,
+// and fn is never called after it returns.
+func (c *Collection) ForEachCtx(ctx context.Context, query, model interface{}, opts IterOptions, fn func() error) (lastID interface{}, err error) {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("model must be a non-nil pointer, got %T", model)
+	}
+
+	// the iteration may outlive invoke once the ctx is done, the lock keeps it from touching model and last then.
+	var mu sync.Mutex
+	last := opts.ResumeAfter
+	defer func() {
+		mu.Lock()
+		lastID = last
+		mu.Unlock()
+	}()
+
+	query = resumeQuery(query, opts.ResumeAfter)
+	err = c.invoke(ctx, OpIter, query, func(col *mgo.Collection) error {
+		q := col.Find(query).Select(opts.Selector).Sort("_id")
+		if opts.BatchSize > 0 {
+			q = q.Batch(opts.BatchSize)
+		}
+
+		iter := q.Iter()
+		var raw bson.Raw
+		for iter.Next(&raw) {
+			mu.Lock()
+			id, err := iterate(ctx, raw, v, fn)
+			if err == nil {
+				last = id
+			}
+			mu.Unlock()
+
+			if err != nil {
+				iter.Close()
+				if cbErr, ok := err.(callbackError); ok && cbErr.err == ErrStopIteration {
+					return nil
+				}
+				return err
+			}
+		}
+		return iter.Close()
+	})
+	return
+}
+
+// iterate unmarshals the raw document into a zeroed model and calls fn, the `_id` of the document is returned
+// if fn succeeds, otherwise the error of fn is wrapped by callbackError.
+func iterate(ctx context.Context, raw bson.Raw, model reflect.Value, fn func() error) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, parseContextError(err)
+	}
+
+	var doc struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err := raw.Unmarshal(&doc); err != nil {
+		return nil, err
+	}
+
+	model.Elem().Set(reflect.Zero(model.Elem().Type()))
+	if err := raw.Unmarshal(model.Interface()); err != nil {
+		return nil, err
+	}
+	if err := fn(); err != nil {
+		return nil, callbackError{err}
+	}
+	return doc.ID, nil
+}
+
+// resumeQuery returns the query of the documents after the `_id`.
+func resumeQuery(query, after interface{}) interface{} {
+	if after == nil {
+		return query
+	}
+
+	cond := bson.M{"_id": bson.M{"$gt": after}}
+	if query == nil {
+		return cond
+	}
+	return bson.M{"$and": []interface{}{query, cond}}
+}
+
+// ForEach iterates the documents match the query in `_id` order without loading them all into memory.
+//
+// See `Collection.ForEach` also.
+func (r *Repository[T]) ForEach(query interface{}, opts IterOptions, fn func(model T) error) (lastID interface{}, err error) {
+	return r.ForEachCtx(context.Background(), query, opts, fn)
+}
+
+// ForEachCtx is the context-aware version of ForEach.
+func (r *Repository[T]) ForEachCtx(ctx context.Context, query interface{}, opts IterOptions, fn func(model T) error) (lastID interface{}, err error) {
+	var model T
+	return r.col.ForEachCtx(ctx, query, &model, opts, func() error {
+		return fn(model)
+	})
+}
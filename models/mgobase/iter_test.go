@@ -0,0 +1,134 @@
+package mgobase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestResumeQuery(t *testing.T) {
+	id := bson.ObjectIdHex("58db2700cf2f6715b00021a7")
+
+	assert.Equal(t, bson.M{"status": "open"}, resumeQuery(bson.M{"status": "open"}, nil))
+	assert.Equal(t, bson.M{"_id": bson.M{"$gt": id}}, resumeQuery(nil, id))
+	assert.Equal(t, bson.M{"$and": []interface{}{
+		bson.M{"status": "open"},
+		bson.M{"_id": bson.M{"$gt": id}},
+	}}, resumeQuery(bson.M{"status": "open"}, id))
+}
+
+func TestIterate(t *testing.T) {
+	id := bson.ObjectIdHex("58db2700cf2f6715b00021a7")
+	data, err := bson.Marshal(bson.M{"_id": id, "name": "jack"})
+	assert.NoError(t, err)
+	raw := bson.Raw{Kind: 0x03, Data: data}
+
+	var model struct {
+		Name string `bson:"name"`
+		Age  int    `bson:"age"`
+	}
+	model.Age = 18
+
+	calls := 0
+	var fnErr error
+	fn := func() error {
+		calls++
+		return fnErr
+	}
+
+	lastID, err := iterate(context.Background(), raw, reflect.ValueOf(&model), fn)
+	assert.NoError(t, err)
+	assert.Equal(t, id, lastID)
+	assert.Equal(t, "jack", model.Name)
+	assert.Equal(t, 0, model.Age, "the model should be zeroed before unmarshalling")
+	assert.Equal(t, 1, calls)
+
+	fnErr = ErrStopIteration
+	lastID, err = iterate(context.Background(), raw, reflect.ValueOf(&model), fn)
+	assert.Equal(t, callbackError{ErrStopIteration}, err)
+	assert.Nil(t, lastID)
+	assert.Equal(t, 2, calls)
+
+	_, err = iterate(context.Background(), bson.Raw{Kind: 0x03, Data: []byte{1}}, reflect.ValueOf(&model), fn)
+	assert.Error(t, err, "the malformed document should fail")
+	assert.Equal(t, 2, calls)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = iterate(canceled, raw, reflect.ValueOf(&model), fn)
+	assert.Equal(t, ErrCanceled, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestForEach(t *testing.T) {
+	col := testCollection(t, "iter")
+
+	type doc struct {
+		ID bson.ObjectId `bson:"_id"`
+		N  int           `bson:"n"`
+	}
+	ids := make([]bson.ObjectId, 5)
+	for i := range ids {
+		ids[i] = bson.NewObjectIdWithTime(time.Unix(int64(i), 0))
+		assert.NoError(t, col.Insert(doc{ID: ids[i], N: i}))
+	}
+
+	var (
+		model doc
+		ns    []int
+	)
+	collect := func(stopAt int, err error) func() error {
+		ns = nil
+		return func() error {
+			if model.N == stopAt {
+				return err
+			}
+			ns = append(ns, model.N)
+			return nil
+		}
+	}
+
+	t.Run("in batches", func(t *testing.T) {
+		lastID, err := col.ForEach(nil, &model, IterOptions{BatchSize: 2}, collect(-1, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, ns)
+		assert.Equal(t, ids[4], lastID)
+
+		lastID, err = col.ForEach(bson.M{"n": bson.M{"$gte": 3}}, &model, IterOptions{BatchSize: 1}, collect(-1, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 4}, ns)
+		assert.Equal(t, ids[4], lastID)
+	})
+
+	t.Run("stop iteration", func(t *testing.T) {
+		lastID, err := col.ForEach(nil, &model, IterOptions{BatchSize: 2}, collect(2, ErrStopIteration))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 1}, ns)
+		assert.Equal(t, ids[1], lastID)
+	})
+
+	t.Run("resume after error", func(t *testing.T) {
+		boom := errors.New("boom")
+		lastID, err := col.ForEach(nil, &model, IterOptions{BatchSize: 2}, collect(3, boom))
+		assert.Equal(t, boom, err)
+		assert.Equal(t, []int{0, 1, 2}, ns)
+		assert.Equal(t, ids[2], lastID)
+
+		lastID, err = col.ForEach(nil, &model, IterOptions{BatchSize: 2, ResumeAfter: lastID}, collect(-1, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 4}, ns)
+		assert.Equal(t, ids[4], lastID)
+	})
+
+	t.Run("errors of fn returned as is", func(t *testing.T) {
+		lastID, err := col.ForEach(nil, &model, IterOptions{}, collect(0, mgo.ErrNotFound))
+		assert.Equal(t, mgo.ErrNotFound, err)
+		assert.Nil(t, lastID)
+	})
+}
@@ -1,6 +1,7 @@
 package mgobase
 
 import (
+	"encoding/base64"
 	"errors"
 	"reflect"
 
@@ -26,8 +27,11 @@ const (
 	PagePrev = "previous"
 )
 
-// Marker is the field which is the query condition in range-based paging query.
+// Marker is the field, or the compound fields, which is the query condition in range-based paging query.
 // Normally, a collection which hopes to use marker pagination should always have a default marker field.
+//
+// The prev and next values could be passed to clients by EncodeCursor, and the marker of the next request
+// could be got by ParseMarker.
 type Marker interface {
 	List(col *mgo.Collection, query, selector, models interface{}, limit int) (prev, next interface{}, err error)
 
@@ -36,32 +40,128 @@ type Marker interface {
 	PrevNext(result interface{}) (prev, next interface{})
 }
 
-type marker struct {
-	field string
-	value interface{}
-	page  string
+// CompoundMarker is the Marker on the compound keys, see NewCompoundMarker.
+type CompoundMarker interface {
+	Marker
+	SortFields() []string
 }
 
+type (
+	marker struct {
+		keys   []markerKey
+		values []interface{}
+		page   string
+	}
+
+	markerKey struct {
+		field string
+		desc  bool
+	}
+)
+
 // NewMarker returns a marker.
 func NewMarker(field string, value interface{}, page string) Marker {
+	var values []interface{}
+	if value != nil {
+		values = []interface{}{value}
+	}
+	return NewCompoundMarker([]string{field}, values, page)
+}
+
+// NewCompoundMarker returns a marker on the compound keys, e.g. `[]string{"-created_at", "-_id"}`,
+// prefix name with dash (-) for descending order. The values are the ones of the keys in order, which are
+// compared lexicographically, so a unique key (e.g. `_id`) should be the last one to break the ties.
+//
+// PrevNext of the compound marker returns the values of every key as `[]interface{}`.
+func NewCompoundMarker(fields []string, values []interface{}, page string) CompoundMarker {
+	keys := make([]markerKey, len(fields))
+	for i, field := range fields {
+		keys[i] = markerKey{
+			field: strings.TrimPrefix(field, "-"),
+			desc:  strings.HasPrefix(field, "-"),
+		}
+	}
 	return &marker{
-		field: field,
-		value: value,
-		page:  page,
+		keys:   keys,
+		values: values,
+		page:   page,
+	}
+}
+
+// ParseMarker returns a marker on the keys with the values decoded from the cursor, see NewCompoundMarker.
+// The cursor is ignored for the first and last pages.
+func ParseMarker(cursor, page string, fields ...string) (CompoundMarker, error) {
+	var values []interface{}
+	if page == PageNext || page == PagePrev {
+		var err error
+		if values, err = DecodeCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+	return NewCompoundMarker(fields, values, page), nil
+}
+
+// EncodeCursor encodes the prev or next value returned by `Marker.PrevNext` into an opaque url-safe token,
+// which keeps the types of values, e.g. time.Time and bson.ObjectId. It returns an empty token for nil.
+func EncodeCursor(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	data, err := bson.Marshal(bson.M{"v": values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes the values of the token returned by EncodeCursor. The documents, arrays and regular
+// expressions are rejected since the values are used in the query conditions.
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var doc struct {
+		Values []interface{} `bson:"v"`
+	}
+	if err = bson.Unmarshal(data, &doc); err != nil || len(doc.Values) == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	for _, value := range doc.Values {
+		switch value.(type) {
+		case bson.M, bson.D, []interface{}, bson.RegEx:
+			return nil, errors.New("invalid cursor")
+		}
 	}
+	return doc.Values, nil
 }
 
 func (m *marker) validate() error {
-	if m.field == "" {
+	if len(m.keys) == 0 {
 		return errors.New("marker's field can't be empty")
 	}
+	for _, key := range m.keys {
+		if key.field == "" {
+			return errors.New("marker's field can't be empty")
+		}
+	}
 
 	switch m.page {
 	case PageFirst, PageLast: // no more validation
 	case PageNext, PagePrev:
-		if m.value == nil {
+		if len(m.values) == 0 {
 			return fmt.Errorf("marker's value can't be nil when page equals to %s", m.page)
 		}
+		if len(m.values) != len(m.keys) {
+			return fmt.Errorf("marker has %d values for %d fields", len(m.values), len(m.keys))
+		}
 	default:
 		return errors.New("invalid page type")
 	}
@@ -72,14 +172,8 @@ func (m *marker) validate() error {
 // QueryStatement makes a range-based query based on the `baseQuery` according to the target page type.
 func (m *marker) QueryStatement(baseQuery interface{}) interface{} {
 	switch m.page {
-	case PageNext:
-		rangeQuery := bson.M{m.field: bson.M{"$gt": m.value}}
-		if baseQuery == nil {
-			return rangeQuery
-		}
-		return bson.M{"$and": []interface{}{baseQuery, rangeQuery}}
-	case PagePrev:
-		rangeQuery := bson.M{m.field: bson.M{"$lt": m.value}}
+	case PageNext, PagePrev:
+		rangeQuery := m.rangeQuery()
 		if baseQuery == nil {
 			return rangeQuery
 		}
@@ -88,11 +182,46 @@ func (m *marker) QueryStatement(baseQuery interface{}) interface{} {
 	return baseQuery
 }
 
+// rangeQuery returns the documents after the values in the order of keys, or before them for the previous page.
+// The compound keys are compared lexicographically, e.g. `(a, b) > (1, 2)` is `a > 1 or (a = 1 and b > 2)`.
+func (m *marker) rangeQuery() bson.M {
+	ors := make([]interface{}, 0, len(m.keys))
+	for i, key := range m.keys {
+		cond := make(bson.M, i+1)
+		for j := 0; j < i; j++ {
+			cond[m.keys[j].field] = bson.M{"$eq": m.values[j]}
+		}
+
+		op := "$gt"
+		if key.desc != (m.page == PagePrev) {
+			op = "$lt"
+		}
+		cond[key.field] = bson.M{op: m.values[i]}
+		ors = append(ors, cond)
+	}
+
+	if len(ors) == 1 {
+		return ors[0].(bson.M)
+	}
+	return bson.M{"$or": ors}
+}
+
+// SortField returns the sort of the first key, see SortFields also.
 func (m *marker) SortField() string {
-	if m.isReverse() {
-		return "-" + m.field
+	return m.SortFields()[0]
+}
+
+// SortFields returns the sorts of keys to query the target page, which are reversed for the previous and last pages.
+func (m *marker) SortFields() []string {
+	sorts := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		if key.desc != m.isReverse() {
+			sorts[i] = "-" + key.field
+		} else {
+			sorts[i] = key.field
+		}
 	}
-	return m.field
+	return sorts
 }
 
 func (m *marker) PrevNext(result interface{}) (prev, next interface{}) {
@@ -105,16 +234,31 @@ func (m *marker) PrevNext(result interface{}) (prev, next interface{}) {
 		return
 	}
 
-	elemt := v.Type().Elem()
-	switch {
-	case elemt.Kind() == reflect.Struct || elemt.Elem().Kind() == reflect.Struct:
-		prev = field(v.Index(0).Interface(), m.field)
-		next = field(v.Index(v.Len()-1).Interface(), m.field)
-	case elemt.Kind() == reflect.Map:
-		prev = v.Index(0).MapIndex(reflect.ValueOf(m.field)).Interface()
-		next = v.Index(v.Len() - 1).MapIndex(reflect.ValueOf(m.field)).Interface()
+	return m.keyValues(v.Index(0)), m.keyValues(v.Index(v.Len() - 1))
+}
+
+// keyValues returns the value of key in the document, or the values of every key as `[]interface{}` if they're compound.
+func (m *marker) keyValues(doc reflect.Value) interface{} {
+	for doc.Kind() == reflect.Ptr || doc.Kind() == reflect.Interface {
+		doc = doc.Elem()
 	}
-	return
+
+	values := make([]interface{}, len(m.keys))
+	for i, key := range m.keys {
+		switch doc.Kind() {
+		case reflect.Struct:
+			values[i] = field(doc.Interface(), key.field)
+		case reflect.Map:
+			if v := doc.MapIndex(reflect.ValueOf(key.field)); v.IsValid() {
+				values[i] = v.Interface()
+			}
+		}
+	}
+
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
 }
 
 func (m *marker) List(col *mgo.Collection, query, selector, models interface{}, limit int) (prev, next interface{}, err error) {
@@ -122,7 +266,7 @@ func (m *marker) List(col *mgo.Collection, query, selector, models interface{},
 		return
 	}
 
-	err = col.Find(m.QueryStatement(query)).Select(selector).Sort(m.SortField()).Limit(limit).All(models)
+	err = col.Find(m.QueryStatement(query)).Select(selector).Sort(m.SortFields()...).Limit(limit).All(models)
 	if err != nil {
 		return
 	}
@@ -322,3 +322,147 @@ func TestMarkerList(t *testing.T) {
 		col.Database.DropDatabase()
 	}
 }
+
+func TestCompoundMarkerQueryStatement(t *testing.T) {
+	var (
+		createdAt = time.Date(2017, 3, 29, 12, 0, 0, 0, time.UTC)
+		id        = bson.NewObjectId()
+		baseQ     = bson.M{"field": "value"}
+	)
+
+	type testcase struct {
+		fields       []string
+		page         string
+		expected     interface{}
+		expectedSort []string
+	}
+
+	testcases := []testcase{
+		{
+			fields:       []string{"-created_at", "-_id"},
+			page:         PageFirst,
+			expected:     baseQ,
+			expectedSort: []string{"-created_at", "-_id"},
+		},
+		{
+			fields:       []string{"-created_at", "-_id"},
+			page:         PageLast,
+			expected:     baseQ,
+			expectedSort: []string{"created_at", "_id"},
+		},
+		{
+			fields: []string{"-created_at", "-_id"},
+			page:   PageNext,
+			expected: bson.M{"$and": []interface{}{baseQ, bson.M{"$or": []interface{}{
+				bson.M{"created_at": bson.M{"$lt": createdAt}},
+				bson.M{"created_at": bson.M{"$eq": createdAt}, "_id": bson.M{"$lt": id}},
+			}}}},
+			expectedSort: []string{"-created_at", "-_id"},
+		},
+		{
+			fields: []string{"-created_at", "-_id"},
+			page:   PagePrev,
+			expected: bson.M{"$and": []interface{}{baseQ, bson.M{"$or": []interface{}{
+				bson.M{"created_at": bson.M{"$gt": createdAt}},
+				bson.M{"created_at": bson.M{"$eq": createdAt}, "_id": bson.M{"$gt": id}},
+			}}}},
+			expectedSort: []string{"created_at", "_id"},
+		},
+		{
+			fields: []string{"created_at", "-_id"},
+			page:   PageNext,
+			expected: bson.M{"$and": []interface{}{baseQ, bson.M{"$or": []interface{}{
+				bson.M{"created_at": bson.M{"$gt": createdAt}},
+				bson.M{"created_at": bson.M{"$eq": createdAt}, "_id": bson.M{"$lt": id}},
+			}}}},
+			expectedSort: []string{"created_at", "-_id"},
+		},
+	}
+
+	for _, tc := range testcases {
+		marker := NewCompoundMarker(tc.fields, []interface{}{createdAt, id}, tc.page)
+		assert.Equal(t, tc.expected, marker.QueryStatement(baseQ))
+		assert.Equal(t, tc.expectedSort, marker.SortFields())
+	}
+
+	desc := NewMarker("-_id", id, PageNext)
+	assert.Equal(t, bson.M{"_id": bson.M{"$lt": id}}, desc.QueryStatement(nil))
+	assert.Equal(t, "-_id", desc.SortField())
+}
+
+func TestCompoundMarkerPrevNext(t *testing.T) {
+	type item struct {
+		ID        string `bson:"_id"`
+		CreatedAt int    `bson:"created_at"`
+	}
+
+	marker := NewCompoundMarker([]string{"-created_at", "-_id"}, nil, PageFirst)
+
+	prev, next := marker.PrevNext([]*item{{"3", 2}, {"2", 1}, {"1", 1}})
+	assert.Equal(t, []interface{}{2, "3"}, prev)
+	assert.Equal(t, []interface{}{1, "1"}, next)
+
+	prev, next = marker.PrevNext([]bson.M{{"_id": "3", "created_at": 2}, {"_id": "1"}})
+	assert.Equal(t, []interface{}{2, "3"}, prev)
+	assert.Equal(t, []interface{}{nil, "1"}, next)
+
+	prev, next = marker.PrevNext([]int{1, 2})
+	assert.Equal(t, []interface{}{nil, nil}, prev)
+	assert.Equal(t, []interface{}{nil, nil}, next)
+}
+
+func TestMarkerCursor(t *testing.T) {
+	var (
+		createdAt = time.Date(2017, 3, 29, 12, 0, 0, 0, time.UTC)
+		id        = bson.NewObjectId()
+	)
+
+	token, err := EncodeCursor([]interface{}{createdAt, id})
+	assert.NoError(t, err)
+	assert.NotContains(t, token, "=")
+
+	values, err := DecodeCursor(token)
+	assert.NoError(t, err)
+	if assert.Len(t, values, 2) {
+		assert.True(t, createdAt.Equal(values[0].(time.Time)))
+		assert.Equal(t, id, values[1])
+	}
+
+	token, err = EncodeCursor("abc")
+	assert.NoError(t, err)
+	values, err = DecodeCursor(token)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"abc"}, values)
+
+	token, err = EncodeCursor(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+
+	_, err = DecodeCursor("not a cursor")
+	assert.EqualError(t, err, "invalid cursor")
+	_, err = DecodeCursor("")
+	assert.EqualError(t, err, "invalid cursor")
+
+	for _, value := range []interface{}{bson.M{"$ne": nil}, []interface{}{1}, bson.RegEx{Pattern: "."}} {
+		token, err = EncodeCursor([]interface{}{value})
+		assert.NoError(t, err)
+		_, err = DecodeCursor(token)
+		assert.EqualError(t, err, "invalid cursor")
+	}
+
+	token, _ = EncodeCursor([]interface{}{createdAt, id})
+	m, err := ParseMarker(token, PageNext, "-created_at", "-_id")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-created_at", "-_id"}, m.SortFields())
+	assert.NoError(t, m.(*marker).validate())
+
+	m, err = ParseMarker("", PageFirst, "-created_at", "-_id")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-created_at", "-_id"}, m.SortFields())
+
+	_, err = ParseMarker("", PageNext, "_id")
+	assert.EqualError(t, err, "invalid cursor")
+
+	m = NewCompoundMarker([]string{"created_at", "_id"}, []interface{}{createdAt}, PageNext)
+	assert.EqualError(t, m.(*marker).validate(), "marker has 1 values for 2 fields")
+}
@@ -0,0 +1,62 @@
+package mgobase
+
+import (
+	"context"
+
+	"github.com/sy264115809/golem/utils/metrics"
+)
+
+type metricsHook struct {
+	operations *metrics.CounterVec
+	durations  *metrics.HistogramVec
+}
+
+// NewMetricsHook returns a QueryHook which records the count and the latency of collection operations into `reg`,
+// metrics.DefaultRegistry will be used if `reg` is nil.
+//
+// The metrics are:
+// - mgo_operations_total{collection, operation, error}
+// - mgo_operation_duration_seconds{collection, operation}
+func NewMetricsHook(reg *metrics.Registry) QueryHook {
+	if reg == nil {
+		reg = metrics.DefaultRegistry
+	}
+	return &metricsHook{
+		operations: reg.NewCounterVec("mgo_operations_total", "Total number of mongo operations.", "collection", "operation", "error"),
+		durations:  reg.NewHistogramVec("mgo_operation_duration_seconds", "Latency of mongo operations in seconds.", nil, "collection", "operation"),
+	}
+}
+
+func (h *metricsHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *metricsHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	h.operations.WithLabelValues(event.Collection, event.Operation, errorKind(event.Err)).Inc()
+	h.durations.WithLabelValues(event.Collection, event.Operation).Observe(event.Duration.Seconds())
+}
+
+// errorKind classifies the error into a label value with limited cardinality.
+func errorKind(err error) string {
+	switch err {
+	case nil, ErrStopIteration:
+		// stopping an iteration early is a success rather than an error
+		return "none"
+	case ErrInvalidID:
+		return "invalid_id"
+	case ErrNotFound:
+		return "not_found"
+	case ErrDuplicateKey:
+		return "duplicate_key"
+	case ErrNotConnected:
+		return "not_connected"
+	case ErrDeadlineExceeded:
+		return "deadline_exceeded"
+	case ErrCanceled:
+		return "canceled"
+	}
+	if isTimeout(err) {
+		return "timeout"
+	}
+	return "other"
+}
@@ -0,0 +1,52 @@
+package mgobase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sy264115809/golem/utils/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHook(t *testing.T) {
+	reg := metrics.NewRegistry()
+	hook := NewMetricsHook(reg)
+
+	ctx := hook.BeforeQuery(context.Background(), &QueryEvent{Collection: "users", Operation: OpFind})
+	assert.Equal(t, context.Background(), ctx)
+
+	hook.AfterQuery(ctx, &QueryEvent{Collection: "users", Operation: OpFind, Duration: 50 * time.Millisecond})
+	hook.AfterQuery(ctx, &QueryEvent{Collection: "users", Operation: OpFind, Duration: 2 * time.Second, Err: ErrNotFound})
+	hook.AfterQuery(ctx, &QueryEvent{Collection: "users", Operation: OpFind, Err: errors.New("boom")})
+
+	var buf bytes.Buffer
+	_, err := reg.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `mgo_operations_total{collection="users",operation="`+OpFind+`",error="none"} 1`)
+	assert.Contains(t, buf.String(), `mgo_operations_total{collection="users",operation="`+OpFind+`",error="not_found"} 1`)
+	assert.Contains(t, buf.String(), `mgo_operations_total{collection="users",operation="`+OpFind+`",error="other"} 1`)
+	assert.Contains(t, buf.String(), `mgo_operation_duration_seconds_count{collection="users",operation="`+OpFind+`"} 3`)
+	assert.Contains(t, buf.String(), `mgo_operation_duration_seconds_sum{collection="users",operation="`+OpFind+`"} 2.05`)
+}
+
+func TestErrorKind(t *testing.T) {
+	testcases := map[error]string{
+		nil:                 "none",
+		ErrStopIteration:    "none",
+		ErrInvalidID:        "invalid_id",
+		ErrNotFound:         "not_found",
+		ErrDuplicateKey:     "duplicate_key",
+		ErrNotConnected:     "not_connected",
+		ErrDeadlineExceeded: "deadline_exceeded",
+		ErrCanceled:         "canceled",
+		errors.New("boom"):  "other",
+	}
+
+	for err, expected := range testcases {
+		assert.Equal(t, expected, errorKind(err), "%v", err)
+	}
+}
@@ -39,7 +39,7 @@ type (
 
 var _ Paginater = paginater{}
 
-// NewPaginater instances a paginater implements Paginater interface.
+// NewPaginater instances a paginater implements Paginater interface, the zero limit means no limit.
 func NewPaginater(skip, limit, count int) Paginater {
 	if skip < 0 {
 		skip = 0
@@ -58,8 +58,9 @@ func NewPaginater(skip, limit, count int) Paginater {
 		count = 0
 	}
 
+	// all of the items are in one page if there is no limit
 	totalPages := 1
-	if count != 0 {
+	if count != 0 && limit > 0 {
 		totalPages = int(math.Ceil(float64(count) / float64(limit)))
 	}
 	return &paginater{
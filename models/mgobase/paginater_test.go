@@ -34,6 +34,16 @@ func TestPaginater(t *testing.T) {
 				{IsPlaceHolder: false, PageNum: 1},
 			},
 		},
+		{
+			desc: "unlimited paginater",
+			skip: 0, limit: 0, count: 95,
+			page: 1, totalItems: 95, totalPages: 1,
+			hasPrev: false, hasNext: false,
+			prevPage: 1, nextPage: 1,
+			iterItems: []PageItem{
+				{IsPlaceHolder: false, PageNum: 1},
+			},
+		},
 		{
 			desc: "normal paginater",
 			skip: 80, limit: 10, count: 166,
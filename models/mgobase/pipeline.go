@@ -0,0 +1,273 @@
+package mgobase
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sy264115809/golem/utils/bsonbuilder"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type (
+	// Pipeline builds the stages of an aggregation pipeline in order, e.g.
+	//
+	//	NewPipeline().Match(query).Group("$status", bson.M{"count": bson.M{"$sum": 1}}).Sort("-count")
+	Pipeline struct {
+		stages []bson.M
+	}
+
+	// AggregateOptions are the options of Aggregate and AggregateIter.
+	AggregateOptions struct {
+		// AllowDiskUse enables the stages to write temporary files, so they could exceed the memory limit of db.
+		AllowDiskUse bool
+		// BatchSize is the number of documents fetched from db in a round trip, the default of db is used if it's 0.
+		BatchSize int
+	}
+)
+
+// NewPipeline returns an empty pipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Stages returns the stages of the pipeline.
+func (p *Pipeline) Stages() []bson.M {
+	return p.stages
+}
+
+// Stage appends a custom stage, e.g. `bson.M{"$count": "total"}`.
+func (p *Pipeline) Stage(stage bson.M) *Pipeline {
+	p.stages = append(p.stages, stage)
+	return p
+}
+
+// Match appends a `$match` stage, the query could be a bsonbuilder.Query or any query document.
+func (p *Pipeline) Match(query interface{}) *Pipeline {
+	if q, ok := query.(bsonbuilder.Query); ok {
+		query = q.ToBSON()
+	}
+	return p.Stage(bson.M{"$match": query})
+}
+
+// Group appends a `$group` stage grouped by id with the accumulated fields,
+// e.g. `Group("$status", bson.M{"count": bson.M{"$sum": 1}})`.
+func (p *Pipeline) Group(id interface{}, fields bson.M) *Pipeline {
+	group := bson.M{"_id": id}
+	for k, v := range fields {
+		group[k] = v
+	}
+	return p.Stage(bson.M{"$group": group})
+}
+
+// Project appends a `$project` stage.
+func (p *Pipeline) Project(fields bson.M) *Pipeline {
+	return p.Stage(bson.M{"$project": fields})
+}
+
+// Sort appends a `$sort` stage, prefix name with dash (-) for descending order.
+func (p *Pipeline) Sort(fields ...string) *Pipeline {
+	sort := make(bson.D, 0, len(fields))
+	for _, field := range fields {
+		order := 1
+		if strings.HasPrefix(field, "-") {
+			field, order = field[1:], -1
+		}
+		sort = append(sort, bson.DocElem{Name: field, Value: order})
+	}
+	return p.Stage(bson.M{"$sort": sort})
+}
+
+// Lookup appends a `$lookup` stage which joins the documents of collection from whose foreignField
+// equals to the localField, the joined documents are set to the array field as.
+func (p *Pipeline) Lookup(from, localField, foreignField, as string) *Pipeline {
+	return p.Stage(bson.M{"$lookup": bson.M{
+		"from":         from,
+		"localField":   localField,
+		"foreignField": foreignField,
+		"as":           as,
+	}})
+}
+
+// Unwind appends a `$unwind` stage which outputs a document for each element of the array field path.
+// The documents whose path is missing, null or an empty array are kept if preserveEmpty is true.
+func (p *Pipeline) Unwind(path string, preserveEmpty bool) *Pipeline {
+	if !strings.HasPrefix(path, "$") {
+		path = "$" + path
+	}
+	if !preserveEmpty {
+		return p.Stage(bson.M{"$unwind": path})
+	}
+	return p.Stage(bson.M{"$unwind": bson.M{
+		"path":                       path,
+		"preserveNullAndEmptyArrays": true,
+	}})
+}
+
+// Facet appends a `$facet` stage which processes the sub-pipelines on the same documents.
+func (p *Pipeline) Facet(facets map[string]*Pipeline) *Pipeline {
+	facet := make(bson.M, len(facets))
+	for name, sub := range facets {
+		facet[name] = sub.Stages()
+	}
+	return p.Stage(bson.M{"$facet": facet})
+}
+
+// Skip appends a `$skip` stage.
+func (p *Pipeline) Skip(n int) *Pipeline {
+	return p.Stage(bson.M{"$skip": n})
+}
+
+// Limit appends a `$limit` stage.
+func (p *Pipeline) Limit(n int) *Pipeline {
+	return p.Stage(bson.M{"$limit": n})
+}
+
+// pipe returns the mgo pipe of the pipeline on the collection.
+func (p *Pipeline) pipe(col *mgo.Collection, opts AggregateOptions) *mgo.Pipe {
+	stages := p.Stages()
+	if stages == nil {
+		stages = []bson.M{}
+	}
+
+	pipe := col.Pipe(stages)
+	if opts.AllowDiskUse {
+		pipe = pipe.AllowDiskUse()
+	}
+	if opts.BatchSize > 0 {
+		pipe = pipe.Batch(opts.BatchSize)
+	}
+	return pipe
+}
+
+// Aggregate runs the aggregation pipeline and unmarshals all the results into models.
+func (c *Collection) Aggregate(pipeline *Pipeline, models interface{}, opts AggregateOptions) error {
+	return c.AggregateCtx(context.Background(), pipeline, models, opts)
+}
+
+// AggregateCtx is the context-aware version of Aggregate.
+func (c *Collection) AggregateCtx(ctx context.Context, pipeline *Pipeline, models interface{}, opts AggregateOptions) error {
+	return c.invokeInto(ctx, OpAggregate, pipeline.Stages(), models, func(col *mgo.Collection, models interface{}) error {
+		return pipeline.pipe(col, opts).All(models)
+	})
+}
+
+// AggregateIter runs the aggregation pipeline and calls fn with the iterator of the results.
+// The iterator is only valid during fn, it's closed after fn returns and the error of iterating is returned.
+// Like `Collection.InvokeCtx`, AggregateIterCtx doesn't wait for fn once the ctx is done.
+func (c *Collection) AggregateIter(pipeline *Pipeline, opts AggregateOptions, fn func(iter *mgo.Iter) error) error {
+	return c.AggregateIterCtx(context.Background(), pipeline, opts, fn)
+}
+
+// AggregateIterCtx is the context-aware version of AggregateIter.
+func (c *Collection) AggregateIterCtx(ctx context.Context, pipeline *Pipeline, opts AggregateOptions, fn func(iter *mgo.Iter) error) error {
+	return c.invoke(ctx, OpAggregate, pipeline.Stages(), func(col *mgo.Collection) error {
+		iter := pipeline.pipe(col, opts).Iter()
+		if err := fn(iter); err != nil {
+			iter.Close()
+			return err
+		}
+		return iter.Close()
+	})
+}
+
+// facetPage is the result of the pipeline appended by paginate.
+type facetPage struct {
+	Items bson.Raw `bson:"items"`
+	Total []struct {
+		Count int `bson:"count"`
+	} `bson:"total"`
+}
+
+// paginate returns a copy of the pipeline appended a `$facet` stage, which outputs a single document
+// with the page of documents projected by selector and the total count of them.
+func (p *Pipeline) paginate(skip, limit int, selector interface{}) *Pipeline {
+	items := NewPipeline()
+	if skip > 0 {
+		items.Skip(skip)
+	}
+	if limit > 0 {
+		items.Limit(limit)
+	}
+	if selector != nil {
+		items.Stage(bson.M{"$project": selector})
+	}
+	if items.stages == nil {
+		// a sub-pipeline of `$facet` can't be empty
+		items.Skip(0)
+	}
+
+	paged := &Pipeline{stages: append([]bson.M(nil), p.stages...)}
+	return paged.Facet(map[string]*Pipeline{
+		"items": items,
+		"total": NewPipeline().Stage(bson.M{"$count": "count"}),
+	})
+}
+
+// decode unmarshals the page of documents into models and returns the total count.
+func (f *facetPage) decode(models interface{}) (int, error) {
+	if f.Items.Kind != 0 {
+		if err := f.Items.Unmarshal(models); err != nil {
+			return 0, err
+		}
+	}
+	if len(f.Total) == 0 {
+		return 0, nil
+	}
+	return f.Total[0].Count, nil
+}
+
+func (c *Collection) aggregatePage(ctx context.Context, paged *Pipeline, models interface{}, skip, limit int, opts AggregateOptions) (Paginater, error) {
+	var page facetPage
+	err := c.invoke(ctx, OpAggregate, paged.Stages(), func(col *mgo.Collection) error {
+		return paged.pipe(col, opts).One(&page)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := page.decode(models)
+	if err != nil {
+		return nil, err
+	}
+	return NewPaginater(skip, limit, count), nil
+}
+
+// AggregateWithPagination runs the aggregation pipeline, skips the `skip` steps and unmarshals the limited results
+// into models. Unlike FindAllWithPagination, the total count is got by `$facet` in the same round trip.
+//
+// `limit` will be concerned if it's greater than 0.
+//
+// `$facet` requires MongoDB 3.4 or later, and it outputs the page as a single document, so the page is capped
+// by the 16MB limit of a document, including the whole results if `limit` isn't greater than 0.
+func (c *Collection) AggregateWithPagination(pipeline *Pipeline, models interface{}, skip, limit int, opts AggregateOptions) (Paginater, error) {
+	return c.AggregateWithPaginationCtx(context.Background(), pipeline, models, skip, limit, opts)
+}
+
+// AggregateWithPaginationCtx is the context-aware version of AggregateWithPagination.
+func (c *Collection) AggregateWithPaginationCtx(ctx context.Context, pipeline *Pipeline, models interface{}, skip, limit int, opts AggregateOptions) (Paginater, error) {
+	return c.aggregatePage(ctx, pipeline.paginate(skip, limit, nil), models, skip, limit, opts)
+}
+
+// FindAllWithFacetPagination works just like FindAllWithPagination, but the documents and the total count are got
+// by an aggregation with `$facet` in one round trip.
+//
+// It requires MongoDB 3.4 or later, and the page is capped by the 16MB limit of a document since `$facet` outputs
+// a single document, including all the documents match the query if `limit` isn't greater than 0.
+func (c *Collection) FindAllWithFacetPagination(query, selector, models interface{}, skip, limit int, sorts ...string) (Paginater, error) {
+	return c.FindAllWithFacetPaginationCtx(context.Background(), query, selector, models, skip, limit, sorts...)
+}
+
+// FindAllWithFacetPaginationCtx is the context-aware version of FindAllWithFacetPagination.
+func (c *Collection) FindAllWithFacetPaginationCtx(ctx context.Context, query, selector, models interface{}, skip, limit int, sorts ...string) (Paginater, error) {
+	if query == nil {
+		query = bson.M{}
+	}
+
+	pipeline := NewPipeline().Match(query)
+	if len(sorts) > 0 {
+		pipeline.Sort(sorts...)
+	}
+	return c.aggregatePage(ctx, pipeline.paginate(skip, limit, selector), models, skip, limit, AggregateOptions{})
+}
@@ -0,0 +1,163 @@
+package mgobase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/sy264115809/golem/utils/bsonbuilder"
+)
+
+func TestPipeline(t *testing.T) {
+	query := bsonbuilder.New()
+	query.Add("status", bsonbuilder.OperatorEq, "open")
+
+	pipeline := NewPipeline().
+		Match(query).
+		Lookup("users", "owner_id", "_id", "owner").
+		Unwind("owner", false).
+		Unwind("$tags", true).
+		Group("$owner._id", bson.M{"count": bson.M{"$sum": 1}}).
+		Project(bson.M{"count": 1}).
+		Sort("-count", "_id").
+		Skip(10).
+		Limit(5).
+		Facet(map[string]*Pipeline{
+			"total": NewPipeline().Stage(bson.M{"$count": "total"}),
+		})
+
+	assert.Equal(t, []bson.M{
+		{"$match": bson.M{"status": bson.M{"$eq": "open"}}},
+		{"$lookup": bson.M{"from": "users", "localField": "owner_id", "foreignField": "_id", "as": "owner"}},
+		{"$unwind": "$owner"},
+		{"$unwind": bson.M{"path": "$tags", "preserveNullAndEmptyArrays": true}},
+		{"$group": bson.M{"_id": "$owner._id", "count": bson.M{"$sum": 1}}},
+		{"$project": bson.M{"count": 1}},
+		{"$sort": bson.D{{Name: "count", Value: -1}, {Name: "_id", Value: 1}}},
+		{"$skip": 10},
+		{"$limit": 5},
+		{"$facet": bson.M{"total": []bson.M{{"$count": "total"}}}},
+	}, pipeline.Stages())
+
+	assert.Equal(t, []bson.M{{"$match": bson.M{"ok": true}}}, NewPipeline().Match(bson.M{"ok": true}).Stages())
+}
+
+func TestPipelinePaginate(t *testing.T) {
+	pipeline := NewPipeline().Match(bson.M{"ok": true}).Sort("-created_at")
+
+	assert.Equal(t, []bson.M{
+		{"$match": bson.M{"ok": true}},
+		{"$sort": bson.D{{Name: "created_at", Value: -1}}},
+		{"$facet": bson.M{
+			"items": []bson.M{{"$skip": 20}, {"$limit": 10}, {"$project": bson.M{"name": 1}}},
+			"total": []bson.M{{"$count": "count"}},
+		}},
+	}, pipeline.paginate(20, 10, bson.M{"name": 1}).Stages())
+
+	assert.Equal(t, bson.M{"$facet": bson.M{
+		"items": []bson.M{{"$skip": 0}},
+		"total": []bson.M{{"$count": "count"}},
+	}}, pipeline.paginate(0, 0, nil).Stages()[2])
+
+	assert.Len(t, pipeline.Stages(), 2, "the pipeline should not be changed")
+}
+
+func TestFacetPageDecode(t *testing.T) {
+	data, err := bson.Marshal(bson.M{
+		"items": []bson.M{{"name": "jack"}, {"name": "mary"}},
+		"total": []bson.M{{"count": 12}},
+	})
+	assert.NoError(t, err)
+
+	var page facetPage
+	assert.NoError(t, bson.Unmarshal(data, &page))
+
+	var models []struct {
+		Name string `bson:"name"`
+	}
+	count, err := page.decode(&models)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, count)
+	if assert.Len(t, models, 2) {
+		assert.Equal(t, "jack", models[0].Name)
+		assert.Equal(t, "mary", models[1].Name)
+	}
+
+	data, err = bson.Marshal(bson.M{"items": []bson.M{}, "total": []bson.M{}})
+	assert.NoError(t, err)
+	page = facetPage{}
+	assert.NoError(t, bson.Unmarshal(data, &page))
+	count, err = page.decode(&models)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Empty(t, models)
+}
+
+func TestAggregate(t *testing.T) {
+	col := testCollection(t, "pipeline")
+
+	type item struct {
+		Name   string `bson:"name"`
+		Status string `bson:"status"`
+		Rank   int    `bson:"rank"`
+	}
+	for i, status := range []string{"open", "closed", "open", "open", "closed"} {
+		assert.NoError(t, col.Insert(item{Name: string(rune('a' + i)), Status: status, Rank: i}))
+	}
+
+	t.Run("aggregate", func(t *testing.T) {
+		var groups []struct {
+			Status string `bson:"_id"`
+			Count  int    `bson:"count"`
+		}
+		pipeline := NewPipeline().Group("$status", bson.M{"count": bson.M{"$sum": 1}}).Sort("-count")
+		assert.NoError(t, col.Aggregate(pipeline, &groups, AggregateOptions{AllowDiskUse: true}))
+		if assert.Len(t, groups, 2) {
+			assert.Equal(t, "open", groups[0].Status)
+			assert.Equal(t, 3, groups[0].Count)
+			assert.Equal(t, "closed", groups[1].Status)
+			assert.Equal(t, 2, groups[1].Count)
+		}
+
+		var names []string
+		err := col.AggregateIter(NewPipeline().Sort("rank"), AggregateOptions{BatchSize: 2}, func(iter *mgo.Iter) error {
+			var it item
+			for iter.Next(&it) {
+				names = append(names, it.Name)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+	})
+
+	t.Run("aggregate with pagination", func(t *testing.T) {
+		var items []item
+		p, err := col.AggregateWithPagination(NewPipeline().Match(bson.M{"status": "open"}).Sort("-rank"), &items, 1, 1, AggregateOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []item{{Name: "c", Status: "open", Rank: 2}}, items)
+		assert.Equal(t, 3, p.TotalItems())
+		assert.Equal(t, 2, p.Page())
+
+		p, err = col.AggregateWithPagination(NewPipeline().Match(bson.M{"status": "unknown"}), &items, 0, 10, AggregateOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, items)
+		assert.Equal(t, 0, p.TotalItems())
+	})
+
+	t.Run("find all with facet pagination", func(t *testing.T) {
+		var items []item
+		p, err := col.FindAllWithFacetPagination(bson.M{"status": "closed"}, bson.M{"_id": 0, "name": 1}, &items, 0, 0, "rank")
+		assert.NoError(t, err)
+		assert.Equal(t, []item{{Name: "b"}, {Name: "e"}}, items)
+		assert.Equal(t, 2, p.TotalItems())
+
+		page, err := NewRepository[item](col).FindAllWithFacetPagination(nil, nil, 2, 2, "-rank")
+		assert.NoError(t, err)
+		assert.Equal(t, []item{{Name: "c", Status: "open", Rank: 2}, {Name: "b", Status: "closed", Rank: 1}}, page.Items)
+		assert.Equal(t, 5, page.TotalItems())
+		assert.Equal(t, 3, page.TotalPages())
+	})
+}
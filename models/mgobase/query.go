@@ -0,0 +1,194 @@
+package mgobase
+
+import (
+	"context"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+type (
+	// Query is a chainable query of a collection, e.g.
+	//
+	//	col.Query(filter).Select(selector).Sort("-created_at").Skip(20).Limit(10).All(&models)
+	//
+	// The query is only sent when one of All, One, Count or Iter is called, which goes through
+	// the collection like the other operations do, so the indexes are ensured and the errors are mapped.
+	Query struct {
+		col      *Collection
+		ctx      context.Context
+		filter   interface{}
+		selector interface{}
+		sorts    []string
+		hint     []string
+		skip     int
+		limit    int
+	}
+
+	// TypedQuery is the typed version of Query returned by `Repository.Query`, every document it reads is a `T`.
+	TypedQuery[T any] struct {
+		q *Query
+	}
+)
+
+// Query returns a chainable query of the documents match the filter.
+func (c *Collection) Query(filter interface{}) *Query {
+	return &Query{
+		col:    c,
+		ctx:    context.Background(),
+		filter: filter,
+	}
+}
+
+// Context sets the context of the query, see `Collection.InvokeCtx` also.
+func (q *Query) Context(ctx context.Context) *Query {
+	q.ctx = ctx
+	return q
+}
+
+// Select sets the projection fields of the query.
+func (q *Query) Select(selector interface{}) *Query {
+	q.selector = selector
+	return q
+}
+
+// Sort appends the sort fields of the query, prefix name with dash (-) for descending order.
+func (q *Query) Sort(fields ...string) *Query {
+	q.sorts = append(q.sorts, fields...)
+	return q
+}
+
+// Skip skips over the n initial documents.
+func (q *Query) Skip(n int) *Query {
+	q.skip = n
+	return q
+}
+
+// Limit restricts the number of documents returned, it will be concerned if it's greater than 0.
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Hint forces the query to use the index of the key fields, prefix name with dash (-) for descending order.
+func (q *Query) Hint(indexKey ...string) *Query {
+	q.hint = indexKey
+	return q
+}
+
+// build builds the mgo query on the collection.
+func (q *Query) build(col *mgo.Collection) *mgo.Query {
+	query := col.Find(q.filter).Select(q.selector).Skip(q.skip).Limit(q.limit).Sort(q.sorts...)
+	if len(q.hint) > 0 {
+		query = query.Hint(q.hint...)
+	}
+	return query
+}
+
+// All unmarshals all documents match the query into models.
+func (q *Query) All(models interface{}) error {
+	return q.col.invokeInto(q.ctx, OpFindAll, q.filter, models, func(col *mgo.Collection, models interface{}) error {
+		return q.build(col).All(models)
+	})
+}
+
+// One unmarshals the first document match the query into model, ErrNotFound is returned if there is none.
+func (q *Query) One(model interface{}) error {
+	return q.col.invokeInto(q.ctx, OpFind, q.filter, model, func(col *mgo.Collection, model interface{}) error {
+		return q.build(col).One(model)
+	})
+}
+
+// Count returns the number of documents match the query, the skip and limit are concerned.
+func (q *Query) Count() (n int, err error) {
+	var count int
+	err = q.col.invoke(q.ctx, OpCount, q.filter, func(col *mgo.Collection) error {
+		var err error
+		count, err = q.build(col).Count()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Iter calls fn with the iterator of the documents match the query.
+// The iterator is only valid during fn, it's closed after fn returns and the error of iterating is returned.
+// Like `Collection.InvokeCtx`, Iter doesn't wait for fn once the ctx is done.
+func (q *Query) Iter(fn func(iter *mgo.Iter) error) error {
+	return q.col.invoke(q.ctx, OpIter, q.filter, func(col *mgo.Collection) error {
+		iter := q.build(col).Iter()
+		if err := fn(iter); err != nil {
+			iter.Close()
+			return err
+		}
+		return iter.Close()
+	})
+}
+
+// Query returns a chainable query of the documents match the filter.
+//
+// See `Collection.Query` also.
+func (r *Repository[T]) Query(filter interface{}) *TypedQuery[T] {
+	return &TypedQuery[T]{
+		q: r.col.Query(filter),
+	}
+}
+
+// Context sets the context of the query.
+func (q *TypedQuery[T]) Context(ctx context.Context) *TypedQuery[T] {
+	q.q.Context(ctx)
+	return q
+}
+
+// Select sets the projection fields of the query.
+func (q *TypedQuery[T]) Select(selector interface{}) *TypedQuery[T] {
+	q.q.Select(selector)
+	return q
+}
+
+// Sort appends the sort fields of the query, prefix name with dash (-) for descending order.
+func (q *TypedQuery[T]) Sort(fields ...string) *TypedQuery[T] {
+	q.q.Sort(fields...)
+	return q
+}
+
+// Skip skips over the n initial documents.
+func (q *TypedQuery[T]) Skip(n int) *TypedQuery[T] {
+	q.q.Skip(n)
+	return q
+}
+
+// Limit restricts the number of documents returned, it will be concerned if it's greater than 0.
+func (q *TypedQuery[T]) Limit(n int) *TypedQuery[T] {
+	q.q.Limit(n)
+	return q
+}
+
+// Hint forces the query to use the index of the key fields.
+func (q *TypedQuery[T]) Hint(indexKey ...string) *TypedQuery[T] {
+	q.q.Hint(indexKey...)
+	return q
+}
+
+// All returns all documents match the query.
+func (q *TypedQuery[T]) All() (models []T, err error) {
+	err = q.q.All(&models)
+	return
+}
+
+// One returns the first document match the query, ErrNotFound is returned if there is none.
+func (q *TypedQuery[T]) One() (model T, err error) {
+	err = q.q.One(&model)
+	return
+}
+
+// Count returns the number of documents match the query, the skip and limit are concerned.
+func (q *TypedQuery[T]) Count() (int, error) {
+	return q.q.Count()
+}
+
+// Iter calls fn with the iterator of the documents match the query, see `Query.Iter` also.
+func (q *TypedQuery[T]) Iter(fn func(iter *mgo.Iter) error) error {
+	return q.q.Iter(fn)
+}
@@ -0,0 +1,76 @@
+package mgobase
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestQueryChain(t *testing.T) {
+	col := &Collection{}
+	q := col.Query(bson.M{"status": "open"}).
+		Select(bson.M{"name": 1}).
+		Sort("-created_at").Sort("_id").
+		Skip(20).
+		Limit(10).
+		Hint("status", "-created_at")
+
+	assert.Equal(t, bson.M{"status": "open"}, q.filter)
+	assert.Equal(t, bson.M{"name": 1}, q.selector)
+	assert.Equal(t, []string{"-created_at", "_id"}, q.sorts)
+	assert.Equal(t, 20, q.skip)
+	assert.Equal(t, 10, q.limit)
+	assert.Equal(t, []string{"status", "-created_at"}, q.hint)
+}
+
+func TestQueryList(t *testing.T) {
+	sess, err := mgo.DialWithTimeout(dsn, 10*time.Second)
+	assert.NoError(t, err)
+	defer sess.Close()
+
+	type item struct {
+		Name   string `bson:"name"`
+		Status string `bson:"status"`
+		Rank   int    `bson:"rank"`
+	}
+
+	col := &Collection{
+		sessionFactory:  sess.Copy,
+		dbName:          "golem_test",
+		colName:         "query",
+		indexes:         []Index{{Key: []string{"status", "-rank"}}},
+		ensureIndexLock: &sync.Mutex{},
+		hooks:           newHookChain(),
+	}
+	defer sess.DB("golem_test").DropDatabase()
+
+	for i, status := range []string{"open", "open", "closed", "open", "open", "open"} {
+		assert.NoError(t, col.Insert(item{Name: string(rune('a' + i)), Status: status, Rank: i}))
+	}
+
+	q := col.Query(bson.M{"status": "open"}).
+		Select(bson.M{"_id": 0, "name": 1}).
+		Sort("-rank").
+		Skip(1).
+		Limit(2).
+		Hint("status", "-rank")
+
+	var items []item
+	assert.NoError(t, q.All(&items))
+	assert.Equal(t, []item{{Name: "e"}, {Name: "d"}}, items)
+
+	var one item
+	assert.NoError(t, q.One(&one))
+	assert.Equal(t, item{Name: "e"}, one)
+
+	n, err := q.Count()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// the hint is sent to the server, which rejects the unknown index
+	assert.Error(t, col.Query(nil).Hint("unknown").All(&items))
+}
@@ -0,0 +1,239 @@
+package mgobase
+
+import (
+	"context"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type (
+	// Repository is a typed wrapper of Collection, every document it reads or writes is a `T`.
+	Repository[T any] struct {
+		col *Collection
+	}
+
+	// Page is a typed page of documents returned by `Repository.FindAllWithPagination`.
+	Page[T any] struct {
+		Items []T
+		Paginater
+	}
+)
+
+// NewRepository returns a Repository of model type `T` built on the collection `col`.
+func NewRepository[T any](col *Collection) *Repository[T] {
+	return &Repository[T]{
+		col: col,
+	}
+}
+
+// Collection returns the underlying collection of the repository.
+func (r *Repository[T]) Collection() *Collection {
+	return r.col
+}
+
+// Insert inserts one or more documents.
+func (r *Repository[T]) Insert(models ...T) error {
+	return r.InsertCtx(context.Background(), models...)
+}
+
+// InsertCtx is the context-aware version of Insert.
+func (r *Repository[T]) InsertCtx(ctx context.Context, models ...T) error {
+	docs := make([]interface{}, len(models))
+	for i := range models {
+		docs[i] = models[i]
+	}
+	return r.col.InsertCtx(ctx, docs...)
+}
+
+// Find finds a single document by given query and sort conditions if exist.
+func (r *Repository[T]) Find(query interface{}, sorts ...string) (model T, err error) {
+	return r.FindCtx(context.Background(), query, sorts...)
+}
+
+// FindCtx is the context-aware version of Find.
+func (r *Repository[T]) FindCtx(ctx context.Context, query interface{}, sorts ...string) (model T, err error) {
+	err = r.col.FindCtx(ctx, query, &model, sorts...)
+	return
+}
+
+// FindByID finds a single document by object id in string form.
+func (r *Repository[T]) FindByID(id string) (model T, err error) {
+	return r.FindByIDCtx(context.Background(), id)
+}
+
+// FindByIDCtx is the context-aware version of FindByID.
+func (r *Repository[T]) FindByIDCtx(ctx context.Context, id string) (model T, err error) {
+	err = r.col.FindByIDCtx(ctx, id, &model)
+	return
+}
+
+// FindByObjectID finds a single document by object id.
+func (r *Repository[T]) FindByObjectID(id bson.ObjectId) (model T, err error) {
+	return r.FindByObjectIDCtx(context.Background(), id)
+}
+
+// FindByObjectIDCtx is the context-aware version of FindByObjectID.
+func (r *Repository[T]) FindByObjectIDCtx(ctx context.Context, id bson.ObjectId) (model T, err error) {
+	err = r.col.FindByObjectIDCtx(ctx, id, &model)
+	return
+}
+
+// FindAll finds all documents match the query.
+//
+// See `Collection.FindAll` also.
+func (r *Repository[T]) FindAll(query, selector interface{}, skip, limit int, sorts ...string) (models []T, err error) {
+	return r.FindAllCtx(context.Background(), query, selector, skip, limit, sorts...)
+}
+
+// FindAllCtx is the context-aware version of FindAll.
+func (r *Repository[T]) FindAllCtx(ctx context.Context, query, selector interface{}, skip, limit int, sorts ...string) (models []T, err error) {
+	err = r.col.FindAllCtx(ctx, query, selector, &models, skip, limit, sorts...)
+	return
+}
+
+// FindAllWithPagination works just like FindAll, but it returns a page which also indicates the informations about pagination.
+func (r *Repository[T]) FindAllWithPagination(query, selector interface{}, skip, limit int, sorts ...string) (*Page[T], error) {
+	return r.FindAllWithPaginationCtx(context.Background(), query, selector, skip, limit, sorts...)
+}
+
+// FindAllWithPaginationCtx is the context-aware version of FindAllWithPagination.
+func (r *Repository[T]) FindAllWithPaginationCtx(ctx context.Context, query, selector interface{}, skip, limit int, sorts ...string) (*Page[T], error) {
+	page := &Page[T]{}
+	p, err := r.col.FindAllWithPaginationCtx(ctx, query, selector, &page.Items, skip, limit, sorts...)
+	if err != nil {
+		return nil, err
+	}
+	page.Paginater = p
+	return page, nil
+}
+
+// FindAllWithFacetPagination works just like FindAllWithPagination, but the documents and the total count are got
+// in one round trip.
+//
+// See `Collection.FindAllWithFacetPagination` also.
+func (r *Repository[T]) FindAllWithFacetPagination(query, selector interface{}, skip, limit int, sorts ...string) (*Page[T], error) {
+	return r.FindAllWithFacetPaginationCtx(context.Background(), query, selector, skip, limit, sorts...)
+}
+
+// FindAllWithFacetPaginationCtx is the context-aware version of FindAllWithFacetPagination.
+func (r *Repository[T]) FindAllWithFacetPaginationCtx(ctx context.Context, query, selector interface{}, skip, limit int, sorts ...string) (*Page[T], error) {
+	page := &Page[T]{}
+	p, err := r.col.FindAllWithFacetPaginationCtx(ctx, query, selector, &page.Items, skip, limit, sorts...)
+	if err != nil {
+		return nil, err
+	}
+	page.Paginater = p
+	return page, nil
+}
+
+// AggregateWithPagination runs the aggregation pipeline whose results are `T`, and returns a page of them.
+//
+// See `Collection.AggregateWithPagination` also.
+func (r *Repository[T]) AggregateWithPagination(pipeline *Pipeline, skip, limit int, opts AggregateOptions) (*Page[T], error) {
+	return r.AggregateWithPaginationCtx(context.Background(), pipeline, skip, limit, opts)
+}
+
+// AggregateWithPaginationCtx is the context-aware version of AggregateWithPagination.
+func (r *Repository[T]) AggregateWithPaginationCtx(ctx context.Context, pipeline *Pipeline, skip, limit int, opts AggregateOptions) (*Page[T], error) {
+	page := &Page[T]{}
+	p, err := r.col.AggregateWithPaginationCtx(ctx, pipeline, &page.Items, skip, limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	page.Paginater = p
+	return page, nil
+}
+
+// FindAllWithMarker uses the query-based paging technology.
+//
+// See `Collection.FindAllWithMarker` also.
+func (r *Repository[T]) FindAllWithMarker(query, selector interface{}, marker Marker, limit int) (models []T, prev, next interface{}, err error) {
+	return r.FindAllWithMarkerCtx(context.Background(), query, selector, marker, limit)
+}
+
+// FindAllWithMarkerCtx is the context-aware version of FindAllWithMarker.
+func (r *Repository[T]) FindAllWithMarkerCtx(ctx context.Context, query, selector interface{}, marker Marker, limit int) (models []T, prev, next interface{}, err error) {
+	prev, next, err = r.col.FindAllWithMarkerCtx(ctx, query, selector, &models, marker, limit)
+	return
+}
+
+// Count returns the total number of documents by query.
+func (r *Repository[T]) Count(query interface{}) (int, error) {
+	return r.CountCtx(context.Background(), query)
+}
+
+// CountCtx is the context-aware version of Count.
+func (r *Repository[T]) CountCtx(ctx context.Context, query interface{}) (int, error) {
+	return r.col.CountCtx(ctx, query)
+}
+
+// Upsert upserts documents by query selector.
+func (r *Repository[T]) Upsert(selector interface{}, update T) (*mgo.ChangeInfo, error) {
+	return r.UpsertCtx(context.Background(), selector, update)
+}
+
+// UpsertCtx is the context-aware version of Upsert.
+func (r *Repository[T]) UpsertCtx(ctx context.Context, selector interface{}, update T) (*mgo.ChangeInfo, error) {
+	return r.col.UpsertCtx(ctx, selector, update)
+}
+
+// UpdateByObjectID updates a single document by object id.
+func (r *Repository[T]) UpdateByObjectID(id bson.ObjectId, update T) error {
+	return r.UpdateByObjectIDCtx(context.Background(), id, update)
+}
+
+// UpdateByObjectIDCtx is the context-aware version of UpdateByObjectID.
+func (r *Repository[T]) UpdateByObjectIDCtx(ctx context.Context, id bson.ObjectId, update T) error {
+	return r.col.UpdateByObjectIDCtx(ctx, id, update)
+}
+
+// UpdateSetByObjectID updates a single document with $set operator by object id.
+func (r *Repository[T]) UpdateSetByObjectID(id bson.ObjectId, update interface{}) error {
+	return r.UpdateSetByObjectIDCtx(context.Background(), id, update)
+}
+
+// UpdateSetByObjectIDCtx is the context-aware version of UpdateSetByObjectID.
+func (r *Repository[T]) UpdateSetByObjectIDCtx(ctx context.Context, id bson.ObjectId, update interface{}) error {
+	return r.col.UpdateSetByObjectIDCtx(ctx, id, update)
+}
+
+// RemoveByID removes a single document by object id in string form.
+func (r *Repository[T]) RemoveByID(id string) error {
+	return r.RemoveByIDCtx(context.Background(), id)
+}
+
+// RemoveByIDCtx is the context-aware version of RemoveByID.
+func (r *Repository[T]) RemoveByIDCtx(ctx context.Context, id string) error {
+	return r.col.RemoveByIDCtx(ctx, id)
+}
+
+// RemoveByObjectID removes a single document by object id.
+func (r *Repository[T]) RemoveByObjectID(id bson.ObjectId) error {
+	return r.RemoveByObjectIDCtx(context.Background(), id)
+}
+
+// RemoveByObjectIDCtx is the context-aware version of RemoveByObjectID.
+func (r *Repository[T]) RemoveByObjectIDCtx(ctx context.Context, id bson.ObjectId) error {
+	return r.col.RemoveByObjectIDCtx(ctx, id)
+}
+
+// Remove removes a single document by query selector.
+func (r *Repository[T]) Remove(selector interface{}) error {
+	return r.RemoveCtx(context.Background(), selector)
+}
+
+// RemoveCtx is the context-aware version of Remove.
+func (r *Repository[T]) RemoveCtx(ctx context.Context, selector interface{}) error {
+	return r.col.RemoveCtx(ctx, selector)
+}
+
+// RemoveAll removes all documents match the query selector.
+func (r *Repository[T]) RemoveAll(selector interface{}) error {
+	return r.RemoveAllCtx(context.Background(), selector)
+}
+
+// RemoveAllCtx is the context-aware version of RemoveAll.
+func (r *Repository[T]) RemoveAllCtx(ctx context.Context, selector interface{}) error {
+	return r.col.RemoveAllCtx(ctx, selector)
+}
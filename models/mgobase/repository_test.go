@@ -0,0 +1,106 @@
+package mgobase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestRepository(t *testing.T) {
+	type user struct {
+		ID   bson.ObjectId `bson:"_id"`
+		Name string        `bson:"name"`
+		Age  int           `bson:"age"`
+	}
+
+	repo := NewRepository[user](testCollection(t, "repository"))
+	users := []user{
+		{ID: bson.NewObjectId(), Name: "a", Age: 10},
+		{ID: bson.NewObjectId(), Name: "b", Age: 20},
+		{ID: bson.NewObjectId(), Name: "c", Age: 30},
+	}
+	assert.NoError(t, repo.Insert(users...))
+
+	t.Run("find", func(t *testing.T) {
+		found, err := repo.FindByID(users[0].ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, users[0], found)
+
+		found, err = repo.FindByObjectID(users[1].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, users[1], found)
+
+		found, err = repo.Find(bson.M{"age": bson.M{"$gt": 10}}, "-age")
+		assert.NoError(t, err)
+		assert.Equal(t, users[2], found)
+
+		_, err = repo.Find(bson.M{"name": "unknown"})
+		assert.Equal(t, ErrNotFound, err)
+
+		_, err = repo.FindByID("unknown")
+		assert.Equal(t, ErrInvalidID, err)
+	})
+
+	t.Run("find all", func(t *testing.T) {
+		all, err := repo.FindAll(nil, nil, 1, 0, "age")
+		assert.NoError(t, err)
+		assert.Equal(t, users[1:], all)
+
+		all, err = repo.FindAll(bson.M{"name": "unknown"}, nil, 0, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, all)
+
+		page, err := repo.FindAllWithPagination(nil, nil, 0, 2, "-age")
+		assert.NoError(t, err)
+		assert.Equal(t, []user{users[2], users[1]}, page.Items)
+		assert.Equal(t, 3, page.TotalItems())
+		assert.Equal(t, 2, page.TotalPages())
+
+		models, prev, next, err := repo.FindAllWithMarker(nil, nil, NewMarker("age", 10, PageNext), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []user{users[1]}, models)
+		assert.Equal(t, 20, prev)
+		assert.Equal(t, 20, next)
+
+		n, err := repo.Count(bson.M{"age": bson.M{"$gte": 20}})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		assert.NoError(t, repo.UpdateSetByObjectID(users[0].ID, bson.M{"age": 11}))
+		users[0].Age = 11
+
+		users[1].Name = "bb"
+		assert.NoError(t, repo.UpdateByObjectID(users[1].ID, users[1]))
+
+		d := user{ID: bson.NewObjectId(), Name: "d", Age: 40}
+		info, err := repo.Upsert(bson.M{"name": "d"}, d)
+		assert.NoError(t, err)
+		assert.Equal(t, d.ID, info.UpsertedId)
+		users = append(users, d)
+
+		all, err := repo.FindAll(nil, nil, 0, 0, "age")
+		assert.NoError(t, err)
+		assert.Equal(t, users, all)
+
+		assert.Equal(t, ErrNotFound, repo.UpdateByObjectID(bson.NewObjectId(), d))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		assert.NoError(t, repo.RemoveByID(users[0].ID.Hex()))
+		assert.NoError(t, repo.RemoveByObjectID(users[1].ID))
+		assert.NoError(t, repo.Remove(bson.M{"name": "c"}))
+		assert.Equal(t, ErrNotFound, repo.RemoveByObjectID(users[1].ID))
+
+		n, err := repo.Count(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		assert.NoError(t, repo.RemoveAll(nil))
+		n, err = repo.Count(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+}
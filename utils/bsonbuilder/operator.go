@@ -2,6 +2,7 @@ package bsonbuilder
 
 import (
 	"fmt"
+	"strconv"
 
 	"gopkg.in/mgo.v2/bson"
 )
@@ -18,8 +19,26 @@ const (
 	OperatorLte
 	OperatorLike
 	OperatorExists
+	OperatorIn
+	OperatorNin
+	OperatorILike
+	OperatorPrefix
+	OperatorSize
+	OperatorAll
+	OperatorElemMatch
+	OperatorType
+	OperatorMod
+	OperatorText
+	OperatorNear
+	OperatorGeoWithin
 )
 
+// KeyText is the key of OperatorText, since the `$text` query applies to the text index rather than a field.
+const KeyText = "$text"
+
+// earthRadius is the equatorial radius of the earth in meters, which converts distances to radians.
+const earthRadius = 6378100.0
+
 // ToBSON converts operator to bson.
 func (op Operator) ToBSON(vals ...interface{}) bson.M {
 	if len(vals) == 0 {
@@ -61,11 +80,7 @@ func (op Operator) ToBSON(vals ...interface{}) bson.M {
 		return bson.M{"$lte": min}
 
 	case OperatorLike:
-		return bson.M{
-			"$regex": bson.RegEx{
-				Pattern: fmt.Sprint(vals[0]),
-			},
-		}
+		return bson.M{"$regex": toLike(vals[0]).Regex()}
 
 	case OperatorExists:
 		exists := true
@@ -78,7 +93,145 @@ func (op Operator) ToBSON(vals ...interface{}) bson.M {
 			"$exists": exists,
 		}
 
+	case OperatorIn:
+		return bson.M{"$in": vals}
+
+	case OperatorNin:
+		return bson.M{"$nin": vals}
+
+	case OperatorILike:
+		like := toLike(vals[0])
+		like.Options = regexOptions("i", like.Options)
+		return bson.M{"$regex": like.Regex()}
+
+	case OperatorPrefix:
+		like := toLike(vals[0])
+		like.Mode = LikeStartsWith
+		return bson.M{"$regex": like.Regex()}
+
+	case OperatorSize:
+		return bson.M{"$size": vals[0]}
+
+	case OperatorAll:
+		return bson.M{"$all": vals}
+
+	case OperatorElemMatch:
+		// the values are the conditions on the fields of array elements, e.g. bson.M{"qty": bson.M{"$gt": 5}}
+		cond := make(bson.M)
+		for _, val := range vals {
+			m, ok := val.(bson.M)
+			if !ok {
+				return nil
+			}
+			for k, v := range m {
+				if exists, ok := cond[k].(bson.M); ok {
+					if vm, ok := v.(bson.M); ok {
+						cond[k] = merge([]bson.M{exists, vm})
+						continue
+					}
+				}
+				cond[k] = v
+			}
+		}
+		return bson.M{"$elemMatch": cond}
+
+	case OperatorType:
+		return bson.M{"$type": vals[0]}
+
+	case OperatorMod:
+		// the values are the divisor and the remainder
+		if len(vals) != 2 || !IsNumeric(vals[0]) || !IsNumeric(vals[1]) {
+			return nil
+		}
+		return bson.M{"$mod": []interface{}{vals[0], vals[1]}}
+
+	case OperatorText:
+		// the values are the search string and the optional language
+		text := bson.M{"$search": fmt.Sprint(vals[0])}
+		if len(vals) > 1 {
+			text["$language"] = fmt.Sprint(vals[1])
+		}
+		return text
+
+	case OperatorNear:
+		// the values are the longitude, the latitude and the optional max distance in meters
+		coords, ok := toFloats(vals)
+		if !ok || len(coords) < 2 {
+			return nil
+		}
+		near := bson.M{"$geometry": point(coords[0], coords[1])}
+		if len(coords) > 2 {
+			near["$maxDistance"] = coords[2]
+		}
+		return bson.M{"$near": near}
+
+	case OperatorGeoWithin:
+		// the values are a circle of `lng, lat, radius in meters`, a box of `lng1, lat1, lng2, lat2`,
+		// or a polygon of at least 3 points
+		coords, ok := toFloats(vals)
+		if !ok {
+			return nil
+		}
+		switch {
+		case len(coords) == 3:
+			return bson.M{"$geoWithin": bson.M{
+				"$centerSphere": []interface{}{[]float64{coords[0], coords[1]}, coords[2] / earthRadius},
+			}}
+		case len(coords) == 4:
+			return bson.M{"$geoWithin": bson.M{
+				"$box": [][]float64{{coords[0], coords[1]}, {coords[2], coords[3]}},
+			}}
+		case len(coords) >= 6 && len(coords)%2 == 0:
+			ring := make([][]float64, 0, len(coords)/2+1)
+			for i := 0; i < len(coords); i += 2 {
+				ring = append(ring, []float64{coords[i], coords[i+1]})
+			}
+			// the ring of polygon must be closed
+			if first, last := ring[0], ring[len(ring)-1]; first[0] != last[0] || first[1] != last[1] {
+				ring = append(ring, first)
+			}
+			return bson.M{"$geoWithin": bson.M{
+				"$geometry": bson.M{"type": "Polygon", "coordinates": [][][]float64{ring}},
+			}}
+		}
+		return nil
+
 	default:
 		return nil
 	}
 }
+
+// toLike returns the Like value of val, or treats it as a regular expression.
+func toLike(val interface{}) Like {
+	switch like := val.(type) {
+	case Like:
+		return like
+	case *Like:
+		if like != nil {
+			return *like
+		}
+	}
+	return Like{Pattern: fmt.Sprint(val)}
+}
+
+// point returns a GeoJSON point.
+func point(lng, lat float64) bson.M {
+	return bson.M{"type": "Point", "coordinates": []float64{lng, lat}}
+}
+
+// toFloats converts the numeric vals to float64, the `ok` is false if any of them isn't numeric.
+func toFloats(vals []interface{}) (floats []float64, ok bool) {
+	floats = make([]float64, len(vals))
+	for i, val := range vals {
+		if !IsNumeric(val) {
+			return nil, false
+		}
+		floats[i], _ = strconv.ParseFloat(fmt.Sprint(val), 64)
+	}
+	return floats, true
+}
+
+// TextScore returns the projection of the text search score into field, which is required to sort by it.
+func TextScore(field string) bson.M {
+	return bson.M{field: bson.M{"$meta": "textScore"}}
+}
@@ -216,6 +216,192 @@ func TestOperatorToBSON(t *testing.T) {
 			vals:     []interface{}{"a", "b", "c"},
 			expected: bson.M{"$regex": bson.RegEx{Pattern: "a"}},
 		},
+		{
+			desc:     "OperatorLike with literal value",
+			op:       bsonbuilder.OperatorLike,
+			vals:     []interface{}{bsonbuilder.Like{Pattern: "a.b", Mode: bsonbuilder.LikeContains, Options: "m"}},
+			expected: bson.M{"$regex": bson.RegEx{Pattern: `a\.b`, Options: "m"}},
+		},
+		{
+			desc:     "OperatorILike with literal value",
+			op:       bsonbuilder.OperatorILike,
+			vals:     []interface{}{bsonbuilder.Like{Pattern: "a.b", Mode: bsonbuilder.LikeEndsWith, Options: "m"}},
+			expected: bson.M{"$regex": bson.RegEx{Pattern: `a\.b$`, Options: "im"}},
+		},
+		{
+			desc:     "OperatorIn with single value",
+			op:       bsonbuilder.OperatorIn,
+			vals:     []interface{}{"value"},
+			expected: bson.M{"$in": []interface{}{"value"}},
+		},
+		{
+			desc:     "OperatorIn with multiple values",
+			op:       bsonbuilder.OperatorIn,
+			vals:     []interface{}{"value1", 2, true},
+			expected: bson.M{"$in": []interface{}{"value1", 2, true}},
+		},
+		{
+			desc:     "OperatorNin with single value",
+			op:       bsonbuilder.OperatorNin,
+			vals:     []interface{}{"value"},
+			expected: bson.M{"$nin": []interface{}{"value"}},
+		},
+		{
+			desc:     "OperatorNin with multiple values",
+			op:       bsonbuilder.OperatorNin,
+			vals:     []interface{}{"value1", 1, false},
+			expected: bson.M{"$nin": []interface{}{"value1", 1, false}},
+		},
+		{
+			desc:     "OperatorILike with single string value",
+			op:       bsonbuilder.OperatorILike,
+			vals:     []interface{}{"abc"},
+			expected: bson.M{"$regex": bson.RegEx{Pattern: "abc", Options: "i"}},
+		},
+		{
+			desc:     "OperatorILike with multiple string value",
+			op:       bsonbuilder.OperatorILike,
+			vals:     []interface{}{"a", "b"},
+			expected: bson.M{"$regex": bson.RegEx{Pattern: "a", Options: "i"}},
+		},
+		{
+			desc:     "OperatorPrefix with single string value",
+			op:       bsonbuilder.OperatorPrefix,
+			vals:     []interface{}{"abc"},
+			expected: bson.M{"$regex": bson.RegEx{Pattern: "^abc"}},
+		},
+		{
+			desc:     "OperatorPrefix with special characters",
+			op:       bsonbuilder.OperatorPrefix,
+			vals:     []interface{}{"a.b*c"},
+			expected: bson.M{"$regex": bson.RegEx{Pattern: `^a\.b\*c`}},
+		},
+		{
+			desc:     "OperatorSize with single value",
+			op:       bsonbuilder.OperatorSize,
+			vals:     []interface{}{3},
+			expected: bson.M{"$size": 3},
+		},
+		{
+			desc:     "OperatorSize with multiple values",
+			op:       bsonbuilder.OperatorSize,
+			vals:     []interface{}{3, 4},
+			expected: bson.M{"$size": 3},
+		},
+		{
+			desc:     "OperatorAll with multiple values",
+			op:       bsonbuilder.OperatorAll,
+			vals:     []interface{}{"a", "b"},
+			expected: bson.M{"$all": []interface{}{"a", "b"}},
+		},
+		{
+			desc:     "OperatorElemMatch with single condition",
+			op:       bsonbuilder.OperatorElemMatch,
+			vals:     []interface{}{bson.M{"qty": bson.M{"$gt": 5}}},
+			expected: bson.M{"$elemMatch": bson.M{"qty": bson.M{"$gt": 5}}},
+		},
+		{
+			desc:     "OperatorElemMatch with multiple conditions",
+			op:       bsonbuilder.OperatorElemMatch,
+			vals:     []interface{}{bson.M{"qty": bson.M{"$gt": 5}}, bson.M{"qty": bson.M{"$lt": 10}}, bson.M{"sku": bson.M{"$eq": "a"}}},
+			expected: bson.M{"$elemMatch": bson.M{"qty": bson.M{"$gt": 5, "$lt": 10}, "sku": bson.M{"$eq": "a"}}},
+		},
+		{
+			desc:     "OperatorElemMatch with non-document value",
+			op:       bsonbuilder.OperatorElemMatch,
+			vals:     []interface{}{"qty"},
+			expected: nil,
+		},
+		{
+			desc:     "OperatorType with alias",
+			op:       bsonbuilder.OperatorType,
+			vals:     []interface{}{"string"},
+			expected: bson.M{"$type": "string"},
+		},
+		{
+			desc:     "OperatorType with number",
+			op:       bsonbuilder.OperatorType,
+			vals:     []interface{}{2},
+			expected: bson.M{"$type": 2},
+		},
+		{
+			desc:     "OperatorMod with divisor and remainder",
+			op:       bsonbuilder.OperatorMod,
+			vals:     []interface{}{4, 0},
+			expected: bson.M{"$mod": []interface{}{4, 0}},
+		},
+		{
+			desc:     "OperatorMod with single value",
+			op:       bsonbuilder.OperatorMod,
+			vals:     []interface{}{4},
+			expected: nil,
+		},
+		{
+			desc:     "OperatorMod with non-numeric values",
+			op:       bsonbuilder.OperatorMod,
+			vals:     []interface{}{"a", "b"},
+			expected: nil,
+		},
+		{
+			desc:     "OperatorText with search string",
+			op:       bsonbuilder.OperatorText,
+			vals:     []interface{}{"coffee shop"},
+			expected: bson.M{"$search": "coffee shop"},
+		},
+		{
+			desc:     "OperatorText with language",
+			op:       bsonbuilder.OperatorText,
+			vals:     []interface{}{"café", "french"},
+			expected: bson.M{"$search": "café", "$language": "french"},
+		},
+		{
+			desc:     "OperatorNear with point",
+			op:       bsonbuilder.OperatorNear,
+			vals:     []interface{}{-73.9667, 40.78},
+			expected: bson.M{"$near": bson.M{"$geometry": bson.M{"type": "Point", "coordinates": []float64{-73.9667, 40.78}}}},
+		},
+		{
+			desc: "OperatorNear with max distance",
+			op:   bsonbuilder.OperatorNear,
+			vals: []interface{}{-73.9667, 40.78, 500},
+			expected: bson.M{"$near": bson.M{
+				"$geometry":    bson.M{"type": "Point", "coordinates": []float64{-73.9667, 40.78}},
+				"$maxDistance": 500.0,
+			}},
+		},
+		{
+			desc:     "OperatorNear with non-numeric values",
+			op:       bsonbuilder.OperatorNear,
+			vals:     []interface{}{"a", 40.78},
+			expected: nil,
+		},
+		{
+			desc:     "OperatorGeoWithin with circle",
+			op:       bsonbuilder.OperatorGeoWithin,
+			vals:     []interface{}{-73.9667, 40.78, 6378100},
+			expected: bson.M{"$geoWithin": bson.M{"$centerSphere": []interface{}{[]float64{-73.9667, 40.78}, 1.0}}},
+		},
+		{
+			desc:     "OperatorGeoWithin with box",
+			op:       bsonbuilder.OperatorGeoWithin,
+			vals:     []interface{}{0, 0, 100, 100},
+			expected: bson.M{"$geoWithin": bson.M{"$box": [][]float64{{0, 0}, {100, 100}}}},
+		},
+		{
+			desc: "OperatorGeoWithin with polygon",
+			op:   bsonbuilder.OperatorGeoWithin,
+			vals: []interface{}{0, 0, 3, 6, 6, 1},
+			expected: bson.M{"$geoWithin": bson.M{"$geometry": bson.M{
+				"type":        "Polygon",
+				"coordinates": [][][]float64{{{0, 0}, {3, 6}, {6, 1}, {0, 0}}},
+			}}},
+		},
+		{
+			desc:     "OperatorGeoWithin with odd coordinates",
+			op:       bsonbuilder.OperatorGeoWithin,
+			vals:     []interface{}{0, 0, 3, 6, 6},
+			expected: nil,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -1,59 +1,192 @@
 package bsonbuilder
 
 import (
+	"sort"
+
 	"github.com/imdario/mergo"
 
 	"gopkg.in/mgo.v2/bson"
 )
 
 // Query can describe a query through the fields, operators and conditions.
+// The conditions which the operator can't convert, e.g. OperatorMod without the divisor and the remainder,
+// are skipped by ToBSON rather than converted to an empty operator document.
 type Query interface {
 	Add(key string, op Operator, val ...interface{})
 	ToBSON() bson.M
 }
 
-type query map[string]map[Operator][]interface{}
+// LogicalQuery is the Query which also supports the negations and the logical groups, the groups could consist
+// of any Query.
+type LogicalQuery interface {
+	Query
+	// Not adds a condition which the value of key must not match, i.e. `{key: {$not: {op: val}}}`.
+	Not(key string, op Operator, val ...interface{})
+	// Or adds a group which at least one of the queries must match.
+	Or(qs ...Query)
+	// And adds a group which all of the queries must match, it's useful to repeat the operators on the same key.
+	And(qs ...Query)
+	// Nor adds a group which none of the queries could match.
+	Nor(qs ...Query)
+}
+
+// the keys of the logical groups.
+const (
+	groupOr  = "$or"
+	groupAnd = "$and"
+	groupNor = "$nor"
+)
 
-var _ Query = make(query)
+type group struct {
+	key     string
+	queries []Query
+}
 
-// New instances a new Query object.
-func New() Query {
-	q := make(query)
-	q.init()
-	return q
+type query struct {
+	conditions map[string]map[Operator][]interface{}
+	negations  map[string]map[Operator][]interface{}
+	groups     []group
 }
 
-func (q *query) init(keys ...string) {
-	if *q == nil {
-		*q = make(query)
+var _ LogicalQuery = new(query)
+
+// New instances a new Query object.
+func New() LogicalQuery {
+	return &query{
+		conditions: make(map[string]map[Operator][]interface{}),
+		negations:  make(map[string]map[Operator][]interface{}),
 	}
-	for _, key := range keys {
-		if (*q)[key] == nil {
-			(*q)[key] = make(map[Operator][]interface{})
-		}
+}
+
+func add(m map[string]map[Operator][]interface{}, key string, op Operator, val ...interface{}) {
+	if m[key] == nil {
+		m[key] = make(map[Operator][]interface{})
 	}
+	m[key][op] = append(m[key][op], val...)
 }
 
 // Add adds a val with given key.
-func (q query) Add(key string, op Operator, val ...interface{}) {
-	q.init(key)
-	q[key][op] = append(q[key][op], val...)
+func (q *query) Add(key string, op Operator, val ...interface{}) {
+	add(q.conditions, key, op, val...)
+}
+
+// Not adds a negative val with given key.
+func (q *query) Not(key string, op Operator, val ...interface{}) {
+	add(q.negations, key, op, val...)
+}
+
+// Or adds a `$or` group.
+func (q *query) Or(qs ...Query) {
+	q.addGroup(groupOr, qs)
+}
+
+// And adds a `$and` group.
+func (q *query) And(qs ...Query) {
+	q.addGroup(groupAnd, qs)
+}
+
+// Nor adds a `$nor` group.
+func (q *query) Nor(qs ...Query) {
+	q.addGroup(groupNor, qs)
+}
+
+func (q *query) addGroup(key string, qs []Query) {
+	if len(qs) > 0 {
+		q.groups = append(q.groups, group{key: key, queries: qs})
+	}
 }
 
 // ToBSON converts Query q to bson query format.
-func (q query) ToBSON() bson.M {
-	q.init()
+// The same groups added more than once are all kept by wrapping the later ones into `$and`.
+// Every negated operator has its own `$not`, the ones on the same key following the first are wrapped into `$and`,
+// since `{key: {$not: {op1: val1, op2: val2}}}` means neither of them is negated.
+func (q *query) ToBSON() bson.M {
 	bm := make(bson.M)
-	for key, ops := range q {
-		qs := make([]bson.M, 0)
-		for op, conditions := range ops {
-			qs = append(qs, op.ToBSON(conditions...))
+	for key, ops := range q.conditions {
+		if cond := toBSON(ops); len(cond) > 0 {
+			bm[key] = cond
+		}
+	}
+
+	var ands []interface{}
+	keys := make([]string, 0, len(q.negations))
+	for key := range q.negations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ops := q.negations[key]
+		for _, op := range sortOperators(ops) {
+			not := op.ToBSON(ops[op]...)
+			if len(not) == 0 {
+				continue
+			}
+
+			neg := bson.M{"$not": not}
+			cond, _ := bm[key].(bson.M)
+			switch {
+			case cond == nil:
+				bm[key] = neg
+			case cond["$not"] == nil:
+				cond["$not"] = neg["$not"]
+			default:
+				ands = append(ands, bson.M{key: neg})
+			}
+		}
+	}
+
+	for _, g := range q.groups {
+		bms := make([]interface{}, 0, len(g.queries))
+		for _, sub := range g.queries {
+			if sub == nil {
+				continue
+			}
+			if sbm := sub.ToBSON(); len(sbm) > 0 {
+				bms = append(bms, sbm)
+			}
+		}
+		if len(bms) == 0 {
+			continue
 		}
-		bm[key] = merge(qs)
+
+		switch {
+		case g.key == groupAnd:
+			ands = append(ands, bms...)
+		case bm[g.key] == nil:
+			bm[g.key] = bms
+		default:
+			ands = append(ands, bson.M{g.key: bms})
+		}
+	}
+	if len(ands) > 0 {
+		bm[groupAnd] = ands
 	}
+
 	return bm
 }
 
+func toBSON(ops map[Operator][]interface{}) bson.M {
+	qs := make([]bson.M, 0, len(ops))
+	for op, conditions := range ops {
+		if q := op.ToBSON(conditions...); len(q) > 0 {
+			qs = append(qs, q)
+		}
+	}
+	return merge(qs)
+}
+
+// sortOperators returns the operators of ops in order, so the query converted is stable.
+func sortOperators(ops map[Operator][]interface{}) []Operator {
+	sorted := make([]Operator, 0, len(ops))
+	for op := range ops {
+		sorted = append(sorted, op)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
 func merge(qs []bson.M) bson.M {
 	bm := make(bson.M)
 	for _, q := range qs {
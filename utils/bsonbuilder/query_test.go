@@ -34,3 +34,161 @@ func TestQueryToBSON(t *testing.T) {
 
 	assert.Equal(t, expected, q.ToBSON())
 }
+
+func TestQueryToBSONWithText(t *testing.T) {
+	q := bsonbuilder.New()
+	q.Add(bsonbuilder.KeyText, bsonbuilder.OperatorText, "coffee")
+	q.Add("location", bsonbuilder.OperatorNear, 1.5, 2.5, 100.0)
+
+	expected := bson.M{
+		"$text": bson.M{"$search": "coffee"},
+		"location": bson.M{"$near": bson.M{
+			"$geometry":    bson.M{"type": "Point", "coordinates": []float64{1.5, 2.5}},
+			"$maxDistance": 100.0,
+		}},
+	}
+
+	assert.Equal(t, expected, q.ToBSON())
+	assert.Equal(t, bson.M{"score": bson.M{"$meta": "textScore"}}, bsonbuilder.TextScore("score"))
+}
+
+// rawQuery is a Query implemented outside of bsonbuilder.
+type rawQuery bson.M
+
+func (q rawQuery) Add(key string, op bsonbuilder.Operator, val ...interface{}) {
+	q[key] = op.ToBSON(val...)
+}
+
+func (q rawQuery) ToBSON() bson.M {
+	return bson.M(q)
+}
+
+func TestQueryToBSONWithGroups(t *testing.T) {
+	newQuery := func(key string, op bsonbuilder.Operator, val ...interface{}) bsonbuilder.Query {
+		q := bsonbuilder.New()
+		q.Add(key, op, val...)
+		return q
+	}
+
+	t.Run("or, and, nor", func(t *testing.T) {
+		q := bsonbuilder.New()
+		q.Add("status", bsonbuilder.OperatorEq, "open")
+		q.Or(newQuery("priority", bsonbuilder.OperatorGt, 3), newQuery("owner", bsonbuilder.OperatorExists, false))
+		q.And(newQuery("age", bsonbuilder.OperatorGt, 10), newQuery("age", bsonbuilder.OperatorGt, 20))
+		q.Nor(newQuery("deleted", bsonbuilder.OperatorEq, true))
+
+		expected := bson.M{
+			"status": bson.M{"$eq": "open"},
+			"$or": []interface{}{
+				bson.M{"priority": bson.M{"$gt": 3}},
+				bson.M{"owner": bson.M{"$exists": false}},
+			},
+			"$and": []interface{}{
+				bson.M{"age": bson.M{"$gt": 10}},
+				bson.M{"age": bson.M{"$gt": 20}},
+			},
+			"$nor": []interface{}{
+				bson.M{"deleted": bson.M{"$eq": true}},
+			},
+		}
+		assert.Equal(t, expected, q.ToBSON())
+	})
+
+	t.Run("repeated groups", func(t *testing.T) {
+		q := bsonbuilder.New()
+		q.Or(newQuery("a", bsonbuilder.OperatorEq, 1), newQuery("b", bsonbuilder.OperatorEq, 2))
+		q.Or(newQuery("c", bsonbuilder.OperatorEq, 3), newQuery("d", bsonbuilder.OperatorEq, 4))
+		q.Or()
+		q.Nor(bsonbuilder.New())
+
+		expected := bson.M{
+			"$or": []interface{}{
+				bson.M{"a": bson.M{"$eq": 1}},
+				bson.M{"b": bson.M{"$eq": 2}},
+			},
+			"$and": []interface{}{
+				bson.M{"$or": []interface{}{
+					bson.M{"c": bson.M{"$eq": 3}},
+					bson.M{"d": bson.M{"$eq": 4}},
+				}},
+			},
+		}
+		assert.Equal(t, expected, q.ToBSON())
+	})
+
+	t.Run("nested groups", func(t *testing.T) {
+		sub := bsonbuilder.New()
+		sub.Or(newQuery("a", bsonbuilder.OperatorEq, 1), newQuery("b", bsonbuilder.OperatorEq, 2))
+
+		q := bsonbuilder.New()
+		q.And(sub, newQuery("c", bsonbuilder.OperatorEq, 3))
+
+		expected := bson.M{
+			"$and": []interface{}{
+				bson.M{"$or": []interface{}{
+					bson.M{"a": bson.M{"$eq": 1}},
+					bson.M{"b": bson.M{"$eq": 2}},
+				}},
+				bson.M{"c": bson.M{"$eq": 3}},
+			},
+		}
+		assert.Equal(t, expected, q.ToBSON())
+	})
+
+	t.Run("custom query in groups", func(t *testing.T) {
+		q := bsonbuilder.New()
+		q.Or(rawQuery{"a": 1}, newQuery("b", bsonbuilder.OperatorEq, 2))
+
+		expected := bson.M{
+			"$or": []interface{}{
+				bson.M{"a": 1},
+				bson.M{"b": bson.M{"$eq": 2}},
+			},
+		}
+		assert.Equal(t, expected, q.ToBSON())
+	})
+
+	t.Run("not", func(t *testing.T) {
+		q := bsonbuilder.New()
+		q.Add("name", bsonbuilder.OperatorExists, true)
+		q.Not("name", bsonbuilder.OperatorLike, "^test")
+		q.Not("age", bsonbuilder.OperatorGt, 60)
+
+		expected := bson.M{
+			"name": bson.M{"$exists": true, "$not": bson.M{"$regex": bson.RegEx{Pattern: "^test"}}},
+			"age":  bson.M{"$not": bson.M{"$gt": 60}},
+		}
+		assert.Equal(t, expected, q.ToBSON())
+	})
+
+	t.Run("unconvertible conditions", func(t *testing.T) {
+		q := bsonbuilder.New()
+		q.Add("qty", bsonbuilder.OperatorMod, 4)
+		q.Add("items", bsonbuilder.OperatorElemMatch, "qty")
+		q.Add("location", bsonbuilder.OperatorNear, "a", "b")
+		q.Not("area", bsonbuilder.OperatorGeoWithin, 1, 2)
+		q.Add("age", bsonbuilder.OperatorGt, 18)
+		q.Add("age", bsonbuilder.OperatorMod, 4)
+
+		expected := bson.M{
+			"age": bson.M{"$gt": 18},
+		}
+		assert.Equal(t, expected, q.ToBSON())
+	})
+
+	t.Run("not twice on the same key", func(t *testing.T) {
+		q := bsonbuilder.New()
+		q.Not("age", bsonbuilder.OperatorLt, 18)
+		q.Not("age", bsonbuilder.OperatorGt, 60)
+		q.Add("age", bsonbuilder.OperatorExists, true)
+
+		// i.e. NOT age > 60 AND NOT age < 18, rather than NOT (age > 60 AND age < 18)
+		expected := bson.M{
+			"age": bson.M{"$exists": true, "$not": bson.M{"$gt": 60}},
+			"$and": []interface{}{
+				bson.M{"age": bson.M{"$not": bson.M{"$lt": 18}}},
+			},
+		}
+		assert.Equal(t, expected, q.ToBSON())
+	})
+}
@@ -0,0 +1,118 @@
+package bsonbuilder
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// LikeMode decides how the pattern of OperatorLike and OperatorILike is matched.
+type LikeMode int
+
+const (
+	// LikeRegex treats the pattern as a regular expression.
+	LikeRegex LikeMode = iota
+	// LikeContains matches the values contain the literal pattern.
+	LikeContains
+	// LikeStartsWith matches the values start with the literal pattern.
+	LikeStartsWith
+	// LikeEndsWith matches the values end with the literal pattern.
+	LikeEndsWith
+)
+
+// Like is the value of OperatorLike and OperatorILike with the match mode and the regex options,
+// the other values are treated as regular expressions without options.
+type Like struct {
+	Pattern string
+	Mode    LikeMode
+	// Options are the regex options among `i`, `m`, `s` and `x`, the others are dropped.
+	Options string
+}
+
+var (
+	// MaxRegexLength is the max length of the regular expressions accepted by ValidateRegex.
+	MaxRegexLength = 256
+	// MaxRegexRepeats is the max number of the repetition operators accepted by ValidateRegex.
+	MaxRegexRepeats = 16
+
+	// ErrRegexTooLong represents the regular expression is longer than MaxRegexLength.
+	ErrRegexTooLong = errors.New("regex is too long")
+	// ErrRegexTooComplex represents the regular expression has nested repetitions, e.g. `(a+)+`,
+	// or more repetitions than MaxRegexRepeats, which may take catastrophic backtracking.
+	ErrRegexTooComplex = errors.New("regex is too complex")
+)
+
+// ValidateRegex checks the length and the complexity of a regular expression provided by clients.
+// The pattern is parsed in Perl syntax without the backtracking-only features, e.g. backreferences and lookarounds,
+// which are rejected as well.
+func ValidateRegex(pattern string) error {
+	if len(pattern) > MaxRegexLength {
+		return ErrRegexTooLong
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return err
+	}
+
+	repeats := 0
+	var walk func(re *syntax.Regexp, repeated bool) bool
+	walk = func(re *syntax.Regexp, repeated bool) bool {
+		switch re.Op {
+		case syntax.OpStar, syntax.OpPlus, syntax.OpRepeat:
+			repeats++
+			if repeated {
+				return false
+			}
+			repeated = true
+		case syntax.OpQuest:
+			repeats++
+		}
+
+		for _, sub := range re.Sub {
+			if !walk(sub, repeated) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !walk(re, false) || repeats > MaxRegexRepeats {
+		return ErrRegexTooComplex
+	}
+	return nil
+}
+
+// Regex returns the bson.RegEx of the like value.
+func (l Like) Regex() bson.RegEx {
+	pattern := l.Pattern
+	switch l.Mode {
+	case LikeContains:
+		pattern = regexp.QuoteMeta(pattern)
+	case LikeStartsWith:
+		pattern = "^" + regexp.QuoteMeta(pattern)
+	case LikeEndsWith:
+		pattern = regexp.QuoteMeta(pattern) + "$"
+	}
+	return bson.RegEx{
+		Pattern: pattern,
+		Options: regexOptions(l.Options),
+	}
+}
+
+// regexOptions returns the valid and unique options in order.
+func regexOptions(options ...string) string {
+	var res []byte
+	for _, o := range []byte("imsx") {
+		for _, opts := range options {
+			if strings.IndexByte(opts, o) >= 0 {
+				res = append(res, o)
+				break
+			}
+		}
+	}
+	return string(res)
+}
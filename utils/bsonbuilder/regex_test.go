@@ -0,0 +1,97 @@
+package bsonbuilder_test
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sy264115809/golem/utils/bsonbuilder"
+)
+
+func TestValidateRegex(t *testing.T) {
+	type testcase struct {
+		desc     string
+		pattern  string
+		expected error
+	}
+
+	testcases := []testcase{
+		{
+			desc:    "Literal",
+			pattern: "@gmail.com",
+		},
+		{
+			desc:    "Anchors and classes",
+			pattern: `^[a-z0-9._%+-]+@example\.(com|org)$`,
+		},
+		{
+			desc:     "Too long",
+			pattern:  strings.Repeat("a", bsonbuilder.MaxRegexLength+1),
+			expected: bsonbuilder.ErrRegexTooLong,
+		},
+		{
+			desc:     "Nested repetitions",
+			pattern:  "(a+)+$",
+			expected: bsonbuilder.ErrRegexTooComplex,
+		},
+		{
+			desc:     "Nested counted repetitions",
+			pattern:  "(a{2,}b*){10}",
+			expected: bsonbuilder.ErrRegexTooComplex,
+		},
+		{
+			desc:     "Too many repetitions",
+			pattern:  strings.Repeat("a*", bsonbuilder.MaxRegexRepeats+1),
+			expected: bsonbuilder.ErrRegexTooComplex,
+		},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expected, bsonbuilder.ValidateRegex(tc.pattern), tc.desc)
+	}
+
+	assert.Error(t, bsonbuilder.ValidateRegex("(a"), "Invalid syntax")
+	assert.Error(t, bsonbuilder.ValidateRegex(`(a)\1`), "Backreference")
+}
+
+func TestLikeRegex(t *testing.T) {
+	type testcase struct {
+		desc     string
+		like     bsonbuilder.Like
+		expected bson.RegEx
+	}
+
+	testcases := []testcase{
+		{
+			desc:     "LikeRegex",
+			like:     bsonbuilder.Like{Pattern: "^a.c"},
+			expected: bson.RegEx{Pattern: "^a.c"},
+		},
+		{
+			desc:     "LikeContains",
+			like:     bsonbuilder.Like{Pattern: "a.c", Mode: bsonbuilder.LikeContains},
+			expected: bson.RegEx{Pattern: `a\.c`},
+		},
+		{
+			desc:     "LikeStartsWith",
+			like:     bsonbuilder.Like{Pattern: "(a)", Mode: bsonbuilder.LikeStartsWith},
+			expected: bson.RegEx{Pattern: `^\(a\)`},
+		},
+		{
+			desc:     "LikeEndsWith",
+			like:     bsonbuilder.Like{Pattern: "a+", Mode: bsonbuilder.LikeEndsWith},
+			expected: bson.RegEx{Pattern: `a\+$`},
+		},
+		{
+			desc:     "Options",
+			like:     bsonbuilder.Like{Pattern: "a", Options: "xmzim"},
+			expected: bson.RegEx{Pattern: "a", Options: "imx"},
+		},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expected, tc.like.Regex(), tc.desc)
+	}
+}
@@ -0,0 +1,335 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are the default histogram buckets in seconds, which fit the latency of most network services.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultRegistry is the registry used when no specific registry is provided.
+var DefaultRegistry = NewRegistry()
+
+type (
+	// Registry holds a set of metrics and renders them in the Prometheus text exposition format.
+	Registry struct {
+		lock    sync.Mutex
+		metrics map[string]metric
+	}
+
+	metric interface {
+		write(w *bufio.Writer)
+	}
+
+	// CounterVec is a set of counters partitioned by label values.
+	CounterVec struct {
+		*vec
+	}
+
+	// Counter is a metric value that only goes up.
+	Counter struct {
+		lock  sync.Mutex
+		value float64
+	}
+
+	// HistogramVec is a set of histograms partitioned by label values.
+	HistogramVec struct {
+		*vec
+		buckets []float64
+	}
+
+	// Histogram counts observations into configurable buckets.
+	Histogram struct {
+		lock   sync.Mutex
+		upper  []float64
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+
+	vec struct {
+		metricName string
+		help       string
+		labels     []string
+
+		lock   sync.RWMutex
+		series map[string]*series
+		create func() interface{}
+	}
+
+	series struct {
+		values []string
+		metric interface{}
+	}
+)
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+	}
+}
+
+// NewCounterVec registers a counter vector with the given name, help text and label names.
+// It returns the registered one if a counter vector with the same name exists, and panics if the name is taken by another kind of metric.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		if cv, ok := m.(*CounterVec); ok {
+			return cv
+		}
+		panic(fmt.Sprintf("metrics: %s has been registered as another kind of metric", name))
+	}
+
+	cv := &CounterVec{
+		vec: newVec(name, help, labels, func() interface{} { return &Counter{} }),
+	}
+	r.metrics[name] = cv
+	return cv
+}
+
+// NewHistogramVec registers a histogram vector with the given name, help text, buckets and label names.
+// DefBuckets will be used if `buckets` is empty.
+// It returns the registered one if a histogram vector with the same name exists, and panics if the name is taken by another kind of metric.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if m, ok := r.metrics[name]; ok {
+		if hv, ok := m.(*HistogramVec); ok {
+			return hv
+		}
+		panic(fmt.Sprintf("metrics: %s has been registered as another kind of metric", name))
+	}
+
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	upper := append([]float64(nil), buckets...)
+	sort.Float64s(upper)
+
+	hv := &HistogramVec{
+		buckets: upper,
+	}
+	hv.vec = newVec(name, help, labels, func() interface{} {
+		return &Histogram{
+			upper:  upper,
+			counts: make([]uint64, len(upper)),
+		}
+	})
+	r.metrics[name] = hv
+	return hv
+}
+
+// WriteTo writes all metrics of the registry to `w` in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.lock.Unlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// WithLabelValues returns the counter for the given label values, creates it if not exists.
+// The count of values must be the same as the count of label names.
+func (cv *CounterVec) WithLabelValues(values ...string) *Counter {
+	return cv.get(values).(*Counter)
+}
+
+func (cv *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, cv.metricName, cv.help, "counter")
+	for _, s := range cv.sorted() {
+		c := s.metric.(*Counter)
+		c.lock.Lock()
+		value := c.value
+		c.lock.Unlock()
+		writeSample(w, cv.metricName, cv.labels, s.values, "", "", value)
+	}
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds the given value to the counter, it's ignored if the value is negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.lock.Lock()
+	c.value += v
+	c.lock.Unlock()
+}
+
+// WithLabelValues returns the histogram for the given label values, creates it if not exists.
+// The count of values must be the same as the count of label names.
+func (hv *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return hv.get(values).(*Histogram)
+}
+
+func (hv *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, hv.metricName, hv.help, "histogram")
+	for _, s := range hv.sorted() {
+		h := s.metric.(*Histogram)
+		h.lock.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		sum, count := h.sum, h.count
+		h.lock.Unlock()
+
+		var cumulative uint64
+		for i, upper := range hv.buckets {
+			cumulative += counts[i]
+			writeSample(w, hv.metricName+"_bucket", hv.labels, s.values, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, hv.metricName+"_bucket", hv.labels, s.values, "le", "+Inf", float64(count))
+		writeSample(w, hv.metricName+"_sum", hv.labels, s.values, "", "", sum)
+		writeSample(w, hv.metricName+"_count", hv.labels, s.values, "", "", float64(count))
+	}
+}
+
+// Observe adds a single observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if i := sort.SearchFloat64s(h.upper, v); i < len(h.upper) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+func newVec(name, help string, labels []string, create func() interface{}) *vec {
+	return &vec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		series:     make(map[string]*series),
+		create:     create,
+	}
+}
+
+func (v *vec) get(values []string) interface{} {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values but got %d", v.metricName, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.lock.RLock()
+	s, ok := v.series[key]
+	v.lock.RUnlock()
+	if ok {
+		return s.metric
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if s, ok = v.series[key]; !ok {
+		s = &series{
+			values: append([]string(nil), values...),
+			metric: v.create(),
+		}
+		v.series[key] = s
+	}
+	return s.metric
+}
+
+func (v *vec) sorted() []*series {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ss := make([]*series, len(keys))
+	for i, key := range keys {
+		ss[i] = v.series[key]
+	}
+	return ss
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, helpEscaper.Replace(help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, label, labelEscaper.Replace(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
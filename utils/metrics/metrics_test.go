@@ -0,0 +1,47 @@
+package metrics_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sy264115809/golem/utils/metrics"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	requests := reg.NewCounterVec("http_requests_total", "Total number of requests.", "method", "status")
+	requests.WithLabelValues("GET", "200").Inc()
+	requests.WithLabelValues("GET", "200").Add(2)
+	requests.WithLabelValues("POST", "500").Inc()
+	requests.WithLabelValues("POST", "500").Add(-1)
+
+	latency := reg.NewHistogramVec("http_request_duration_seconds", "Request latency.", []float64{1, 0.1}, "path")
+	latency.WithLabelValues(`/a"b`).Observe(0.05)
+	latency.WithLabelValues(`/a"b`).Observe(0.5)
+	latency.WithLabelValues(`/a"b`).Observe(5)
+
+	assert.Equal(t, requests, reg.NewCounterVec("http_requests_total", ""))
+	assert.Panics(t, func() { reg.NewHistogramVec("http_requests_total", "", nil) })
+	assert.Panics(t, func() { requests.WithLabelValues("GET") })
+
+	expected := `# HELP http_request_duration_seconds Request latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{path="/a\"b",le="0.1"} 1
+http_request_duration_seconds_bucket{path="/a\"b",le="1"} 2
+http_request_duration_seconds_bucket{path="/a\"b",le="+Inf"} 3
+http_request_duration_seconds_sum{path="/a\"b"} 5.55
+http_request_duration_seconds_count{path="/a\"b"} 3
+# HELP http_requests_total Total number of requests.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",status="200"} 3
+http_requests_total{method="POST",status="500"} 1
+`
+
+	var buf bytes.Buffer
+	n, err := reg.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(expected)), n)
+	assert.Equal(t, expected, buf.String())
+}